@@ -0,0 +1,151 @@
+// Package await coordinates several asynchronous operations at once, such as
+// futures and channels, waiting for all or any of them under a context
+// deadline.
+package await
+
+import (
+	"context"
+	"errors"
+
+	"github.com/1core-dev/cloud-native/concurrency-patterns/future"
+	"github.com/1core-dev/cloud-native/concurrency-patterns/result"
+)
+
+var (
+	// ErrClosed signals that a channel was closed before it delivered a value.
+	ErrClosed = errors.New("channel closed without a value")
+
+	// ErrNoTasks signals that Any was given no task to wait for.
+	ErrNoTasks = errors.New("no tasks to await")
+)
+
+// Task is an asynchronous operation that can be awaited. It blocks until the
+// operation completes or ctx is done.
+type Task[T any] func(ctx context.Context) (T, error)
+
+// Future adapts a future.Future to a Task.
+func Future(f future.Future) Task[string] {
+	return func(ctx context.Context) (string, error) {
+		ch := make(chan result.Result[string], 1)
+
+		// Result doesn't accept a context, so wait for it in the background
+		go func() {
+			ch <- result.Of(f.Result())
+		}()
+
+		select {
+		case res := <-ch:
+			return res.Unwrap()
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
+
+// Chan adapts a channel to a Task that completes with the first value
+// received from ch, or ErrClosed if ch is closed first.
+func Chan[T any](ch <-chan T) Task[T] {
+	return func(ctx context.Context) (T, error) {
+		var zero T
+
+		select {
+		case v, ok := <-ch:
+			if !ok {
+				return zero, ErrClosed
+			}
+			return v, nil
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+	}
+}
+
+// indexed carries a task's result together with its position.
+type indexed[T any] struct {
+	idx int
+	res result.Result[T]
+}
+
+// start runs every task in its own goroutine, delivering results on the
+// returned channel, which is buffered so no goroutine is left blocked.
+func start[T any](ctx context.Context, tasks []Task[T]) <-chan indexed[T] {
+	ch := make(chan indexed[T], len(tasks))
+
+	for i, t := range tasks {
+		go func(i int, t Task[T]) {
+			v, err := t(ctx)
+			ch <- indexed[T]{i, result.Of(v, err)}
+		}(i, t)
+	}
+
+	return ch
+}
+
+// All waits for every task to complete and returns their results, indexed
+// like tasks.
+//
+// If ctx is done first, All returns the results collected so far together
+// with ctx.Err(), which is also the error of every task not completed yet.
+func All[T any](ctx context.Context, tasks ...Task[T]) ([]result.Result[T], error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		results = make([]result.Result[T], len(tasks))
+		done    = make([]bool, len(tasks))
+	)
+	ch := start(ctx, tasks)
+
+	for range tasks {
+		select {
+		case r := <-ch:
+			results[r.idx], done[r.idx] = r.res, true
+		case <-ctx.Done():
+			for i := range results {
+				if !done[i] {
+					results[i] = result.Fail[T](ctx.Err())
+				}
+			}
+			return results, ctx.Err()
+		}
+	}
+
+	return results, nil
+}
+
+// Any returns the index and value of the first task to complete successfully,
+// cancelling the others.
+//
+// If every task fails, Any returns -1 and the joined errors. If ctx is done
+// first, it returns -1 and ctx.Err(). With no tasks, it returns -1 and
+// ErrNoTasks, since none can succeed.
+func Any[T any](ctx context.Context, tasks ...Task[T]) (int, T, error) {
+	if len(tasks) == 0 {
+		var zero T
+		return -1, zero, ErrNoTasks
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		zero T
+		errs = make([]error, len(tasks))
+	)
+
+	ch := start(ctx, tasks)
+
+	for range tasks {
+		select {
+		case r := <-ch:
+			if r.res.Err == nil {
+				return r.idx, r.res.Value, nil
+			}
+			errs[r.idx] = r.res.Err
+		case <-ctx.Done():
+			return -1, zero, ctx.Err()
+		}
+	}
+
+	return -1, zero, errors.Join(errs...)
+}
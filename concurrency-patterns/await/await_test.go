@@ -0,0 +1,23 @@
+package await
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestAnyWithoutTasks(t *testing.T) {
+	if i, _, err := Any[int](context.Background()); i != -1 || !errors.Is(err, ErrNoTasks) {
+		t.Fatalf("Any() = %d, %v; want -1, ErrNoTasks", i, err)
+	}
+}
+
+func TestAnyFirstSuccess(t *testing.T) {
+	fail := func(context.Context) (int, error) { return 0, errors.New("fail") }
+	ok := func(context.Context) (int, error) { return 7, nil }
+
+	i, v, err := Any(context.Background(), fail, ok)
+	if i != 1 || v != 7 || err != nil {
+		t.Fatalf("Any = %d, %d, %v; want 1, 7, <nil>", i, v, err)
+	}
+}
@@ -0,0 +1,131 @@
+// Package carrier carries observability metadata (trace and span IDs,
+// deadlines) alongside values flowing through channels.
+//
+// A context.Context can't be sent through fan-in, fan-out, or pipeline
+// stages together with each item. Instead, Attach captures the metadata of
+// the producer's context into a Carrier, and Carrier.Context rebuilds an
+// equivalent context on the consumer side.
+package carrier
+
+import (
+	"context"
+	"maps"
+	"time"
+
+	"github.com/1core-dev/cloud-native/concurrency-patterns/pipeline"
+)
+
+// Well-known metadata keys.
+const (
+	TraceID = "trace-id"
+	SpanID  = "span-id"
+)
+
+// Metadata is a set of string key-value pairs. It is treated as immutable
+// once stored in a context or Carrier.
+type Metadata map[string]string
+
+type metadataKey struct{}
+
+// WithValue returns a copy of ctx whose metadata also maps key to value.
+func WithValue(ctx context.Context, key, value string) context.Context {
+	md := maps.Clone(FromContext(ctx))
+	if md == nil {
+		md = make(Metadata, 1)
+	}
+	md[key] = value
+
+	return context.WithValue(ctx, metadataKey{}, md)
+}
+
+// Value returns the metadata value for key in ctx, or "".
+func Value(ctx context.Context, key string) string {
+	return FromContext(ctx)[key]
+}
+
+// FromContext returns the metadata stored in ctx, or nil. The result must
+// not be modified.
+func FromContext(ctx context.Context) Metadata {
+	md, _ := ctx.Value(metadataKey{}).(Metadata)
+	return md
+}
+
+// Carrier is a value together with the metadata and deadline of the context
+// it was produced in.
+type Carrier[T any] struct {
+	Value    T
+	Metadata Metadata
+	Deadline time.Time // zero if the producer had no deadline
+}
+
+// New returns a Carrier holding v and the metadata and deadline of ctx.
+func New[T any](ctx context.Context, v T) Carrier[T] {
+	c := Carrier[T]{Value: v, Metadata: FromContext(ctx)}
+	if d, ok := ctx.Deadline(); ok {
+		c.Deadline = d
+	}
+	return c
+}
+
+// Context returns a child of parent carrying c's metadata and, if c has one,
+// its deadline. The returned cancel function must be called to release it.
+func (c Carrier[T]) Context(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx := parent
+	if c.Metadata != nil {
+		ctx = context.WithValue(ctx, metadataKey{}, c.Metadata)
+	}
+
+	if c.Deadline.IsZero() {
+		return context.WithCancel(ctx)
+	}
+	return context.WithDeadline(ctx, c.Deadline)
+}
+
+// Attach wraps every value from in into a Carrier holding ctx's metadata.
+// The output channel is closed when in is closed or ctx is done.
+func Attach[T any](ctx context.Context, in <-chan T) <-chan Carrier[T] {
+	out := make(chan Carrier[T])
+
+	go func() {
+		defer close(out)
+
+		for v := range in {
+			select {
+			case out <- New(ctx, v):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// Detach unwraps the values from in, dropping their metadata.
+func Detach[T any](in <-chan Carrier[T]) <-chan T {
+	out := make(chan T)
+
+	go func() {
+		defer close(out)
+
+		for c := range in {
+			out <- c.Value
+		}
+	}()
+
+	return out
+}
+
+// Stage adapts a pipeline stage function to carried values. fn runs with a
+// context carrying each item's metadata and deadline, and the result keeps
+// the item's metadata, so it survives every hop through the pipeline.
+func Stage[In, Out any](fn pipeline.StageFunc[In, Out]) pipeline.StageFunc[Carrier[In], Carrier[Out]] {
+	return func(ctx context.Context, c Carrier[In]) (Carrier[Out], error) {
+		ctx, cancel := c.Context(ctx)
+		defer cancel()
+
+		v, err := fn(ctx, c.Value)
+
+		return Carrier[Out]{Value: v, Metadata: c.Metadata, Deadline: c.Deadline}, err
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/1core-dev/cloud-native/concurrency-patterns/result"
 )
 
 // Chord accepts multiple source channels and returns a single destination channel
@@ -20,18 +22,41 @@ import (
 // are only sent once all channels have emitted.
 
 func Chord(sources ...<-chan int) <-chan []int {
+	return join(sources)
+}
+
+// Results is Chord for sources that carry errors. Each emitted Result holds
+// one value from every source, or the first error among them.
+func Results[T any](sources ...<-chan result.Result[T]) <-chan result.Result[[]T] {
+	joined := join(sources)
+	dest := make(chan result.Result[[]T])
+
+	go func() {
+		defer close(dest)
+
+		for rs := range joined {
+			dest <- result.Collect(rs)
+		}
+	}()
+
+	return dest
+}
+
+// join implements Chord for any element type.
+func join[T any](sources []<-chan T) <-chan []T {
 	type input struct { // Used to send inputs between goroutines
-		idx, input int
+		idx   int
+		input T
 	}
 
-	dest := make(chan []int)   // The output channel
+	dest := make(chan []T)     // The output channel
 	inputs := make(chan input) // An intermediate channel
 	wg := sync.WaitGroup{}     // Used to close channels when all sources are closed
 	wg.Add(len(sources))
 
 	// Start goroutines to collect values from each source channel
 	for i, ch := range sources {
-		go func(i int, ch <-chan int) {
+		go func(i int, ch <-chan T) {
 			defer wg.Done() // Notify WaitGroup when ch is closed
 
 			for n := range ch {
@@ -48,7 +73,7 @@ func Chord(sources ...<-chan int) <-chan []int {
 
 	// Collect values from 'inputs' and emit when all sources have sent data
 	go func() {
-		res := make([]int, len(sources))   // Slice for incoming inputs
+		res := make([]T, len(sources))     // Slice for incoming inputs
 		sent := make([]bool, len(sources)) // Slice to track sent status
 		count := len(sources)              // Counter for channels
 
@@ -61,7 +86,7 @@ func Chord(sources ...<-chan int) <-chan []int {
 			}
 
 			if count == 0 {
-				c := make([]int, len(res)) // Copy and send inputs slice
+				c := make([]T, len(res)) // Copy and send inputs slice
 				copy(c, res)
 				dest <- c
 
@@ -0,0 +1,307 @@
+// Package dlq keeps the items a consumer or worker pool gave up on in a
+// bounded in-memory dead-letter queue, where they can be listed, inspected,
+// retried, or purged, through the API or an HTTP debug endpoint.
+//
+// It suits development and services without external dead-letter
+// infrastructure. Once full, the queue drops its oldest entries.
+package dlq
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotFound signals that no entry has the requested ID, because it was
+	// never added, or was retried, purged, or dropped since.
+	ErrNotFound = errors.New("dead letter not found")
+
+	// ErrRetrying signals that the entry is already being retried.
+	ErrRetrying = errors.New("dead letter retry in progress")
+)
+
+// Entry is a dead-lettered item with what is known about its failure.
+type Entry[T any] struct {
+	ID       uint64            `json:"id"`
+	Item     T                 `json:"item"`
+	Err      string            `json:"error"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Added    time.Time         `json:"added"`
+	Retries  int               `json:"retries"`            // failed retries from the queue
+	Retrying bool              `json:"retrying,omitempty"` // a retry is in flight
+}
+
+// Stats counts what happened to the queue's entries.
+type Stats struct {
+	Len     int    `json:"len"`
+	Added   uint64 `json:"added"`
+	Retried uint64 `json:"retried"` // retried successfully and removed
+	Purged  uint64 `json:"purged"`
+	Dropped uint64 `json:"dropped"` // evicted to make room
+}
+
+// Queue is a bounded dead-letter queue. It is safe for concurrent use.
+type Queue[T any] struct {
+	max   int
+	retry func(T) error
+
+	mu      sync.Mutex
+	entries []Entry[T] // oldest first
+	nextID  uint64
+	stats   Stats
+}
+
+// New returns a Queue holding at most max entries, which retries an entry by
+// passing its item to retry. A nil retry disables retrying.
+func New[T any](max int, retry func(T) error) *Queue[T] {
+	return &Queue[T]{max: max, retry: retry, nextID: 1}
+}
+
+// Add dead-letters item, which failed with err, and returns its ID. If the
+// queue is full, the oldest entry is dropped.
+func (q *Queue[T]) Add(item T, err error, metadata map[string]string) uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	e := Entry[T]{
+		ID:       q.nextID,
+		Item:     item,
+		Metadata: metadata,
+		Added:    time.Now(),
+	}
+	if err != nil {
+		e.Err = err.Error()
+	}
+	q.nextID++
+
+	if q.max > 0 && len(q.entries) >= q.max {
+		q.entries = q.entries[1:]
+		q.stats.Dropped++
+	}
+	q.entries = append(q.entries, e)
+	q.stats.Added++
+
+	return e.ID
+}
+
+// List returns every entry, oldest first.
+func (q *Queue[T]) List() []Entry[T] {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	entries := make([]Entry[T], len(q.entries))
+	copy(entries, q.entries)
+	return entries
+}
+
+// Get returns the entry with the given ID.
+func (q *Queue[T]) Get(id uint64) (Entry[T], bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	i := q.index(id)
+	if i < 0 {
+		return Entry[T]{}, false
+	}
+	return q.entries[i], true
+}
+
+// Retry passes the item of the entry with the given ID to the retry
+// function, and removes the entry if it succeeds. If it fails, the entry
+// stays with the new error. The queue is not locked during the retry, but
+// the entry is claimed: retrying it again meanwhile returns ErrRetrying, so
+// its item is never handed to the retry function twice at once.
+func (q *Queue[T]) Retry(id uint64) error {
+	if q.retry == nil {
+		return errors.New("dead letter retry not configured")
+	}
+
+	q.mu.Lock()
+	i := q.index(id)
+	if i < 0 {
+		q.mu.Unlock()
+		return ErrNotFound
+	}
+	if q.entries[i].Retrying {
+		q.mu.Unlock()
+		return ErrRetrying
+	}
+	q.entries[i].Retrying = true
+	item := q.entries[i].Item
+	q.mu.Unlock()
+
+	returned := false
+	defer func() {
+		if !returned {
+			q.release(id) // The retry function panicked
+		}
+	}()
+	err := q.retry(item)
+	returned = true
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	i = q.index(id)
+	if i < 0 {
+		return err // Purged or dropped meanwhile
+	}
+
+	if err != nil {
+		q.entries[i].Retrying = false
+		q.entries[i].Err = err.Error()
+		q.entries[i].Retries++
+		return err
+	}
+
+	q.remove(i)
+	q.stats.Retried++
+	return nil
+}
+
+// release drops the retry claim on the entry with the given ID, if it is
+// still queued.
+func (q *Queue[T]) release(id uint64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if i := q.index(id); i >= 0 {
+		q.entries[i].Retrying = false
+	}
+}
+
+// Purge removes the entry with the given ID, reporting whether it existed.
+func (q *Queue[T]) Purge(id uint64) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	i := q.index(id)
+	if i < 0 {
+		return false
+	}
+	q.remove(i)
+	q.stats.Purged++
+	return true
+}
+
+// PurgeAll removes every entry and returns how many there were.
+func (q *Queue[T]) PurgeAll() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n := len(q.entries)
+	q.entries = nil
+	q.stats.Purged += uint64(n)
+	return n
+}
+
+// Stats returns the queue's counters.
+func (q *Queue[T]) Stats() Stats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	s := q.stats
+	s.Len = len(q.entries)
+	return s
+}
+
+// index returns the position of the entry with the given ID, or -1. IDs
+// increase with position, so it searches by bisection. Callers must hold q.mu.
+func (q *Queue[T]) index(id uint64) int {
+	lo, hi := 0, len(q.entries)
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if q.entries[mid].ID < id {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	if lo < len(q.entries) && q.entries[lo].ID == id {
+		return lo
+	}
+	return -1
+}
+
+// remove deletes the entry at i. Callers must hold q.mu.
+func (q *Queue[T]) remove(i int) {
+	q.entries = append(q.entries[:i:i], q.entries[i+1:]...)
+}
+
+// ServeHTTP exposes the queue as a debug endpoint:
+//
+//	GET                  stats and every entry
+//	GET    ?id=N         one entry
+//	POST   ?id=N         retry an entry
+//	DELETE ?id=N         purge an entry
+//	DELETE               purge every entry
+func (q *Queue[T]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var (
+		id    uint64
+		hasID = r.URL.Query().Has("id")
+	)
+	if hasID {
+		var err error
+		if id, err = strconv.ParseUint(r.URL.Query().Get("id"), 10, 64); err != nil {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+	}
+
+	switch {
+	case r.Method == http.MethodGet && hasID:
+		e, ok := q.Get(id)
+		if !ok {
+			http.Error(w, ErrNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		writeJSON(w, e)
+
+	case r.Method == http.MethodGet:
+		writeJSON(w, struct {
+			Stats   Stats      `json:"stats"`
+			Entries []Entry[T] `json:"entries"`
+		}{q.Stats(), q.List()})
+
+	case r.Method == http.MethodPost && hasID:
+		if err := q.Retry(id); errors.Is(err, ErrNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else if errors.Is(err, ErrRetrying) {
+			http.Error(w, err.Error(), http.StatusConflict)
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+		} else {
+			w.WriteHeader(http.StatusNoContent)
+		}
+
+	case r.Method == http.MethodDelete && hasID:
+		if !q.Purge(id) {
+			http.Error(w, ErrNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case r.Method == http.MethodDelete:
+		q.PurgeAll()
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// writeJSON writes v as a JSON response.
+func writeJSON(w http.ResponseWriter, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
@@ -0,0 +1,52 @@
+package dlq
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRetryClaimsTheEntry(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	runs := 0
+	q := New(10, func(string) error {
+		runs++
+		close(started)
+		<-release
+		return nil
+	})
+	id := q.Add("job", errors.New("failed"), nil)
+
+	first := make(chan error, 1)
+	go func() { first <- q.Retry(id) }()
+	<-started
+
+	rec := httptest.NewRecorder()
+	q.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/?id=1", nil))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("second retry answered %d, want 409", rec.Code)
+	}
+
+	close(release)
+	if err := <-first; err != nil || runs != 1 {
+		t.Fatalf("first retry = %v after %d runs; want success after 1", err, runs)
+	}
+	if _, ok := q.Get(id); ok {
+		t.Fatal("entry still queued after a successful retry")
+	}
+}
+
+func TestRetryReleasesTheClaimOnPanic(t *testing.T) {
+	q := New(10, func(string) error { panic("boom") })
+	id := q.Add("job", errors.New("failed"), nil)
+
+	func() {
+		defer func() { recover() }()
+		q.Retry(id)
+	}()
+
+	if e, _ := q.Get(id); e.Retrying {
+		t.Fatal("entry left claimed after its retry panicked")
+	}
+}
@@ -32,13 +32,13 @@ func Funnel(sources ...<-chan int) <-chan int {
 				dest <- n
 			}
 		}(ch)
+	}
 
-		go func() { // Start a goroutine to close dest after all sources close
-			wg.Wait()
-			close(dest)
-		}()
+	go func() { // Start a goroutine to close dest after all sources close
+		wg.Wait()
+		close(dest)
+	}()
 
-	}
 	return dest
 }
 
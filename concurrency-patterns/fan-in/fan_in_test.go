@@ -0,0 +1,43 @@
+package fanin
+
+import "testing"
+
+// BenchmarkFunnel measures the cost per value forwarded through a Funnel of
+// four sources. Allocations are those of setting up the funnel, amortized
+// over b.N values; forwarding itself allocates nothing.
+func BenchmarkFunnel(b *testing.B) {
+	const nSources = 4
+
+	b.ReportAllocs()
+
+	sources := make([]<-chan int, nSources)
+	for i := range sources {
+		ch := make(chan int)
+		sources[i] = ch
+
+		go func(n int) {
+			defer close(ch)
+			for j := 0; j < n; j++ {
+				ch <- j
+			}
+		}(b.N/nSources + boolToInt(i < b.N%nSources))
+	}
+
+	b.ResetTimer()
+
+	received := 0
+	for range Funnel(sources...) {
+		received++
+	}
+
+	if received != b.N {
+		b.Fatalf("received %d values, want %d", received, b.N)
+	}
+}
+
+func boolToInt(v bool) int {
+	if v {
+		return 1
+	}
+	return 0
+}
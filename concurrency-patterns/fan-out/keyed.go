@@ -0,0 +1,285 @@
+package fanout
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// queueSize bounds how many values per output may be buffered between the
+// input and the consumers before the input stops being read.
+const queueSize = 16
+
+// Keyed distributes values from a single input across outputs by key, so
+// every value with the same key goes to the same output in input order.
+//
+// Unlike Split, outputs do not compete for input: each key is bound to one
+// output. The number of outputs can be changed with Rescale without breaking
+// per-key ordering.
+type Keyed[T any] struct {
+	key       func(T) string
+	rescale   chan rescaleRequest[T]
+	delivered chan delivery[T]
+	done      chan struct{}
+
+	mu      sync.Mutex
+	outputs []<-chan T
+}
+
+// rescaleRequest asks the dispatcher to change the number of outputs.
+type rescaleRequest[T any] struct {
+	n     int
+	reply chan []<-chan T
+}
+
+// delivery reports that a lane handed a value for key to its consumer.
+type delivery[T any] struct {
+	key  string
+	lane *lane[T]
+}
+
+// flight tracks the values of one key that were queued but not yet delivered.
+type flight[T any] struct {
+	lane *lane[T]
+	n    int
+}
+
+// SplitByKey distributes values from source to n outputs, choosing the output
+// for each value by hashing key(value).
+//
+// All outputs are closed after source is closed and every queued value has
+// been delivered.
+func SplitByKey[T any](source <-chan T, n int, key func(T) string) *Keyed[T] {
+	k := &Keyed[T]{
+		key:       key,
+		rescale:   make(chan rescaleRequest[T]),
+		delivered: make(chan delivery[T]),
+		done:      make(chan struct{}),
+	}
+
+	lanes := k.grow(nil, n)
+	k.outputs = outputsOf(lanes)
+
+	go k.dispatch(source, lanes)
+
+	return k
+}
+
+// Outputs returns the current output channels.
+func (k *Keyed[T]) Outputs() []<-chan T {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.outputs
+}
+
+// Rescale changes the number of outputs to n and returns the new outputs.
+//
+// Outputs that exist both before and after keep their channels. When scaling
+// down, removed outputs are closed once their queued values are delivered.
+// Keys that move to a different output are paused: their new values are held
+// back until every value already queued on the old output has been
+// delivered, so consumers never see a key's values out of order.
+func (k *Keyed[T]) Rescale(n int) []<-chan T {
+	req := rescaleRequest[T]{n: n, reply: make(chan []<-chan T, 1)}
+
+	select {
+	case k.rescale <- req:
+		return <-req.reply
+	case <-k.done:
+		return k.Outputs() // Input finished; nothing left to rescale
+	}
+}
+
+// dispatch routes values from source to lanes and handles rescaling.
+func (k *Keyed[T]) dispatch(source <-chan T, lanes []*lane[T]) {
+	defer close(k.done)
+
+	var (
+		inflight = make(map[string]*flight[T]) // queued, undelivered values per key
+		held     = make(map[string][]T)        // values of paused keys
+		retiring = make(map[*lane[T]]bool)     // lanes removed by a scale-down
+		queued   int                           // total queued values
+	)
+
+	// enqueue hands v to the lane currently responsible for its key
+	enqueue := func(key string, v T) {
+		f := inflight[key]
+		if f == nil {
+			f = &flight[T]{lane: lanes[index(key, len(lanes))]}
+			inflight[key] = f
+		}
+
+		f.n++
+		f.lane.queued++
+		queued++
+		f.lane.push(key, v)
+	}
+
+	for source != nil || queued > 0 || len(held) > 0 {
+		src := source
+		if queued >= queueSize*len(lanes) {
+			src = nil // Apply backpressure until consumers catch up
+		}
+
+		select {
+		case v, ok := <-src:
+			if !ok {
+				source = nil
+				continue
+			}
+
+			key := k.key(v)
+			if _, paused := held[key]; paused {
+				held[key] = append(held[key], v)
+				continue
+			}
+			enqueue(key, v)
+
+		case d := <-k.delivered:
+			queued--
+			d.lane.queued--
+
+			if retiring[d.lane] && d.lane.queued == 0 {
+				delete(retiring, d.lane)
+				d.lane.close()
+			}
+
+			f := inflight[d.key]
+			f.n--
+			if f.n > 0 {
+				continue
+			}
+			delete(inflight, d.key)
+
+			// Old output drained: release the paused key to its new output
+			if vs, paused := held[d.key]; paused {
+				delete(held, d.key)
+				for _, v := range vs {
+					enqueue(d.key, v)
+				}
+			}
+
+		case req := <-k.rescale:
+			if req.n < 1 {
+				req.n = 1
+			}
+
+			if req.n > len(lanes) {
+				lanes = k.grow(lanes, req.n)
+			} else {
+				for _, l := range lanes[req.n:] {
+					if l.queued == 0 {
+						l.close()
+					} else {
+						retiring[l] = true
+					}
+				}
+				lanes = lanes[:req.n]
+			}
+
+			// Pause keys whose queued values sit on a different output now
+			for key, f := range inflight {
+				if _, paused := held[key]; !paused && lanes[index(key, len(lanes))] != f.lane {
+					held[key] = nil
+				}
+			}
+
+			outputs := outputsOf(lanes)
+			k.mu.Lock()
+			k.outputs = outputs
+			k.mu.Unlock()
+
+			req.reply <- outputs
+		}
+	}
+
+	for _, l := range lanes {
+		l.close()
+	}
+}
+
+// grow appends lanes until there are n of them.
+func (k *Keyed[T]) grow(lanes []*lane[T], n int) []*lane[T] {
+	for len(lanes) < n {
+		l := newLane[T]()
+		go l.run(k.delivered)
+		lanes = append(lanes, l)
+	}
+
+	return lanes
+}
+
+// lane is one output: a queue drained into an unbuffered channel.
+type lane[T any] struct {
+	ch     chan T
+	mu     sync.Mutex
+	cond   *sync.Cond
+	items  []laneItem[T]
+	closed bool
+	queued int // undelivered values; owned by the dispatcher
+}
+
+// laneItem is a queued value together with its key.
+type laneItem[T any] struct {
+	key string
+	v   T
+}
+
+func newLane[T any]() *lane[T] {
+	l := &lane[T]{ch: make(chan T)}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// push queues v for delivery without blocking.
+func (l *lane[T]) push(key string, v T) {
+	l.mu.Lock()
+	l.items = append(l.items, laneItem[T]{key, v})
+	l.mu.Unlock()
+	l.cond.Signal()
+}
+
+// close closes the output once its queue is empty.
+func (l *lane[T]) close() {
+	l.mu.Lock()
+	l.closed = true
+	l.mu.Unlock()
+	l.cond.Signal()
+}
+
+// run delivers queued values in order and reports each delivery.
+func (l *lane[T]) run(delivered chan<- delivery[T]) {
+	for {
+		l.mu.Lock()
+		for len(l.items) == 0 && !l.closed {
+			l.cond.Wait()
+		}
+		if len(l.items) == 0 {
+			l.mu.Unlock()
+			close(l.ch)
+			return
+		}
+		it := l.items[0]
+		l.items = l.items[1:]
+		l.mu.Unlock()
+
+		l.ch <- it.v
+		delivered <- delivery[T]{it.key, l}
+	}
+}
+
+// outputsOf returns the output channels of lanes.
+func outputsOf[T any](lanes []*lane[T]) []<-chan T {
+	outputs := make([]<-chan T, len(lanes))
+	for i, l := range lanes {
+		outputs[i] = l.ch
+	}
+	return outputs
+}
+
+// index maps key to one of n outputs using FNV-1a hashing.
+func index(key string, n int) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return int(hash.Sum32() % uint32(n))
+}
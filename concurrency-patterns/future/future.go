@@ -8,6 +8,9 @@ import (
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/1core-dev/cloud-native/concurrency-patterns/panics"
+	"github.com/1core-dev/cloud-native/concurrency-patterns/result"
 )
 
 // Future is an interface that represents an asynchronous operation. It
@@ -21,12 +24,10 @@ type Future interface {
 // InnerFuture is a concrete implementation of Future that holds the result
 // of an asynchronous operation and ensures the result is computed only once.
 type InnerFuture struct {
-	once  sync.Once
-	wg    sync.WaitGroup
-	res   string
-	err   error
-	resCh <-chan string
-	errCh <-chan error
+	once sync.Once
+	wg   sync.WaitGroup
+	res  result.Result[string]
+	ch   <-chan result.Result[string]
 }
 
 // Result waits for the async operation to complete, retrieves the result,
@@ -41,14 +42,13 @@ func (f *InnerFuture) Result() (string, error) {
 		defer f.wg.Done()
 
 		// Wait for the result and any potential error to be available.
-		f.res = <-f.resCh
-		f.err = <-f.errCh
+		f.res = <-f.ch
 	})
 
 	// Block until the result is ready.
 	f.wg.Wait()
 
-	return f.res, f.err
+	return f.res.Unwrap()
 }
 
 // SlowFunction starts an asynchronous task that will take some time to finish.
@@ -56,27 +56,37 @@ func (f *InnerFuture) Result() (string, error) {
 // provides a result. The function returns a Future that can be used to
 // retrieve the result later.
 func SlowFunction(ctx context.Context) Future {
-	resCh := make(chan string)
-	errCh := make(chan error)
+	ch := make(chan result.Result[string])
 
 	// Perform the long-running operation asynchronously in a separate goroutine.
 	go func() {
-		select {
-		// Simulate a task that takes 2 seconds to complete.
-		case <-time.After(2 * time.Second):
-			// Once the task is completed, send the result and indicate no error.
-			resCh <- "I slept for 2 seconds"
-			errCh <- nil
-		// If the operation is cancelled, handle it by sending an error.
-		case <-ctx.Done():
-			// Propagate the cancellation error through the channel.
-			resCh <- ""
-			errCh <- ctx.Err()
+		var (
+			res string
+			err error
+		)
+
+		// A panic is reported through the panics package and surfaces as
+		// the future's error, so Result never blocks forever.
+		if perr := panics.Catch("future", func() {
+			select {
+			// Simulate a task that takes 2 seconds to complete.
+			case <-time.After(2 * time.Second):
+				// Once the task is completed, set the result and indicate no error.
+				res, err = "I slept for 2 seconds", nil
+			// If the operation is cancelled, handle it by setting an error.
+			case <-ctx.Done():
+				// Propagate the cancellation error.
+				res, err = "", ctx.Err()
+			}
+		}); perr != nil {
+			res, err = "", perr
 		}
+
+		ch <- result.Of(res, err)
 	}()
 
 	// Return the Future so that the caller can wait for the result.
-	return &InnerFuture{resCh: resCh, errCh: errCh}
+	return &InnerFuture{ch: ch}
 }
 
 func main() {
@@ -0,0 +1,88 @@
+// Package inflight counts active requests and tasks so a service can wait for
+// them to finish before shutting down or switching traffic away.
+//
+// Work is tracked explicitly with Start or via HTTP middleware. Drain blocks
+// until nothing is in flight or its context deadline passes, which is what a
+// graceful shutdown or blue-green switch needs.
+package inflight
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// Tracker counts in-flight work. The zero value is ready to use.
+type Tracker struct {
+	mu    sync.Mutex
+	count int
+	idle  chan struct{} // closed when count drops back to zero
+}
+
+// Start records the beginning of a unit of work and returns a function that
+// records its end. The returned function is safe to call more than once;
+// only the first call has an effect.
+func (t *Tracker) Start() (done func()) {
+	t.mu.Lock()
+	if t.count == 0 {
+		t.idle = make(chan struct{})
+	}
+	t.count++
+	t.mu.Unlock()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(t.finish)
+	}
+}
+
+// finish records the end of a unit of work.
+func (t *Tracker) finish() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.count--
+	if t.count == 0 {
+		close(t.idle) // Wake up any Drain callers
+		t.idle = nil
+	}
+}
+
+// Count returns the number of units of work currently in flight.
+func (t *Tracker) Count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.count
+}
+
+// Drain blocks until no work is in flight or ctx is done, in which case it
+// returns ctx.Err(). Drain does not stop new work from starting; callers
+// should stop accepting work first.
+func (t *Tracker) Drain(ctx context.Context) error {
+	t.mu.Lock()
+	if t.count == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	idle := t.idle
+	t.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Middleware returns an http.Handler that tracks every request served by next.
+func (t *Tracker) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		done := t.Start()
+		defer done()
+
+		next.ServeHTTP(w, r)
+	})
+}
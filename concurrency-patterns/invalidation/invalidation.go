@@ -0,0 +1,188 @@
+// Package invalidation fans out cache key invalidations from writers to every
+// subscribed cache.
+//
+// Writers publish the keys they changed on a Bus, and each subscribed cache
+// drops its copy right away instead of serving it until its TTL expires. A Bus
+// can be bridged to a pub/sub transport such as Redis so that caches in other
+// replicas converge as well.
+package invalidation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// Invalidator is anything that can drop a cached key, typically a cache.
+type Invalidator interface {
+	Invalidate(key string)
+}
+
+// InvalidatorFunc adapts a function to an Invalidator.
+type InvalidatorFunc func(key string)
+
+// Invalidate calls f(key).
+func (f InvalidatorFunc) Invalidate(key string) {
+	f(key)
+}
+
+// Bus delivers published invalidations to every subscriber. The zero value is
+// ready to use.
+type Bus struct {
+	mu       sync.RWMutex
+	next     int
+	subs     map[int]Invalidator
+	forwards map[int]func(keys []string) // bridges to remote replicas
+}
+
+// Subscribe registers inv to receive every invalidation published on the bus
+// and returns a function that removes it again.
+//
+// Invalidations are delivered synchronously from Publish, so Invalidate must
+// be fast and must not block. It runs outside the bus's lock, so it may
+// subscribe or unsubscribe; an invalidation already being delivered can still
+// reach a subscriber that has just been removed.
+func (b *Bus) Subscribe(inv Invalidator) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subs == nil {
+		b.subs = make(map[int]Invalidator)
+	}
+
+	id := b.next
+	b.next++
+	b.subs[id] = inv
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		delete(b.subs, id)
+	}
+}
+
+// Publish invalidates keys in every subscriber, and in remote replicas if the
+// bus is bridged.
+func (b *Bus) Publish(keys ...string) {
+	b.deliver(keys)
+
+	// Forward outside the lock: a slow remote must not hold up subscriptions
+	b.mu.RLock()
+	forwards := slices.Collect(maps.Values(b.forwards))
+	b.mu.RUnlock()
+
+	for _, forward := range forwards {
+		forward(keys)
+	}
+}
+
+// deliver invalidates keys in local subscribers only.
+func (b *Bus) deliver(keys []string) {
+	b.mu.RLock()
+	subs := slices.Collect(maps.Values(b.subs))
+	b.mu.RUnlock()
+
+	for _, inv := range subs {
+		for _, key := range keys {
+			inv.Invalidate(key)
+		}
+	}
+}
+
+// addForward registers a bridge forwarder and returns a function removing it.
+func (b *Bus) addForward(f func(keys []string)) (remove func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.forwards == nil {
+		b.forwards = make(map[int]func(keys []string))
+	}
+
+	id := b.next
+	b.next++
+	b.forwards[id] = f
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		delete(b.forwards, id)
+	}
+}
+
+// Remote is a pub/sub transport connecting buses in different replicas. It is
+// typically a thin adapter over a Redis client's PUBLISH and SUBSCRIBE.
+type Remote interface {
+	// Publish sends payload to every subscriber of channel.
+	Publish(ctx context.Context, channel string, payload []byte) error
+
+	// Subscribe returns the payloads published to channel until ctx is done.
+	Subscribe(ctx context.Context, channel string) (<-chan []byte, error)
+}
+
+// message is the payload exchanged over a Remote.
+type message struct {
+	Origin string   `json:"origin"` // bridge that published the keys
+	Keys   []string `json:"keys"`
+}
+
+// Bridge connects the bus to remote on channel until ctx is done: keys
+// published locally are sent to other replicas, and keys they publish are
+// delivered to local subscribers.
+//
+// Bridge returns once the subscription is established. Errors publishing to
+// remote are logged; local delivery is never affected by them.
+func (b *Bus) Bridge(ctx context.Context, remote Remote, channel string) error {
+	msgs, err := remote.Subscribe(ctx, channel)
+	if err != nil {
+		return err
+	}
+
+	origin := newOrigin()
+
+	remove := b.addForward(func(keys []string) {
+		payload, err := json.Marshal(message{Origin: origin, Keys: keys})
+		if err == nil {
+			err = remote.Publish(ctx, channel, payload)
+		}
+		if err != nil {
+			log.Printf("invalidation: publish to %q failed: %v", channel, err)
+		}
+	})
+
+	go func() {
+		defer remove()
+
+		for {
+			select {
+			case payload, ok := <-msgs:
+				if !ok {
+					return
+				}
+
+				var m message
+				if err := json.Unmarshal(payload, &m); err != nil || m.Origin == origin {
+					continue // Malformed, or our own publication echoed back
+				}
+				b.deliver(m.Keys)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// newOrigin returns a random identifier for a bridge.
+func newOrigin() string {
+	var buf [8]byte
+	rand.Read(buf[:])
+	return hex.EncodeToString(buf[:])
+}
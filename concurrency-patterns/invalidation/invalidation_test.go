@@ -0,0 +1,34 @@
+package invalidation
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInvalidateMayUnsubscribe(t *testing.T) {
+	var (
+		b     Bus
+		got   []string
+		unsub func()
+	)
+	unsub = b.Subscribe(InvalidatorFunc(func(key string) {
+		got = append(got, key)
+		unsub() // Re-enters the bus
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		b.Publish("a")
+		b.Publish("b")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Publish deadlocked on a subscriber unsubscribing itself")
+	}
+
+	if len(got) != 1 || got[0] != "a" {
+		t.Fatalf("subscriber got %v, want only the key published before it left", got)
+	}
+}
@@ -0,0 +1,162 @@
+// Package panics handles panics in background goroutines the same way
+// everywhere in a process. Patterns that run user code off the caller's
+// goroutine, such as the worker pool and futures, recover panics through
+// this package, which hands them to the handlers registered once at startup:
+// log them, count them, or abort the process.
+package panics
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// Info describes a recovered panic.
+type Info struct {
+	Source string // the pattern or component that recovered it
+	Value  any    // the value passed to panic
+	Stack  []byte // the stack of the panicking goroutine
+}
+
+// Error is returned by Catch when the function panicked.
+type Error struct {
+	Info
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: panic: %v", e.Source, e.Value)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *Error) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// Handler is called with every recovered panic. Handlers run synchronously
+// on the goroutine that panicked; they must not panic themselves.
+type Handler func(Info)
+
+// Log is a Handler that logs the panic and its stack with log.Printf. It is
+// registered by default.
+func Log(info Info) {
+	log.Printf("%s: panic: %v\n%s", info.Source, info.Value, info.Stack)
+}
+
+// registered is a Handler with the ID Handle assigned it.
+type registered struct {
+	id int
+	h  Handler
+}
+
+var (
+	mu       sync.RWMutex
+	handlers = []registered{{0, Log}}
+	nextID   = 1
+
+	abort atomic.Bool
+	count atomic.Uint64
+)
+
+// Handle registers h for every panic recovered from now on, after the
+// handlers already registered. It returns a function that unregisters h.
+func Handle(h Handler) (remove func()) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	id := nextID
+	nextID++
+	handlers = append(handlers, registered{id, h})
+
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+
+		handlers = slices.DeleteFunc(handlers, func(r registered) bool {
+			return r.id == id
+		})
+	}
+}
+
+// Reset unregisters every handler, including the default Log, so a process
+// can install its own set from scratch.
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	handlers = nil
+}
+
+// SetAbort makes every recovered panic crash the process once the handlers
+// have run, by panicking again with the same value. Use it where a panic
+// means state can no longer be trusted; the handlers still get to log and
+// count it first.
+func SetAbort(on bool) {
+	abort.Store(on)
+}
+
+// Count returns the number of panics recovered since the process started.
+func Count() uint64 {
+	return count.Load()
+}
+
+// Recover recovers a panic, if any, and reports it under source. It must be
+// deferred directly:
+//
+//	go func() {
+//		defer panics.Recover("scheduler")
+//		...
+//	}()
+func Recover(source string) {
+	if v := recover(); v != nil {
+		report(source, v, debug.Stack())
+	}
+}
+
+// Go runs fn in a new goroutine, recovering and reporting any panic under
+// source.
+func Go(source string, fn func()) {
+	go func() {
+		defer Recover(source)
+		fn()
+	}()
+}
+
+// Catch runs fn and, if it panics, reports the panic under source and
+// returns it as an *Error, so the caller can pass it on where a result was
+// expected.
+func Catch(source string, fn func()) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			info := report(source, v, debug.Stack())
+			err = &Error{Info: info}
+		}
+	}()
+
+	fn()
+
+	return nil
+}
+
+// report runs the handlers for a recovered panic, then aborts if enabled.
+func report(source string, v any, stack []byte) Info {
+	info := Info{Source: source, Value: v, Stack: stack}
+	count.Add(1)
+
+	mu.RLock()
+	hs := slices.Clone(handlers)
+	mu.RUnlock()
+
+	for _, r := range hs {
+		r.h(info)
+	}
+
+	if abort.Load() {
+		panic(v)
+	}
+
+	return info
+}
@@ -0,0 +1,151 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/1core-dev/cloud-native/manageability-patterns/state"
+)
+
+// CheckpointStore persists the positions of a Checkpointer, keyed by source.
+// A Redis or SQL store can be plugged in by implementing it.
+type CheckpointStore interface {
+	// Load returns the saved positions; none if nothing was saved yet.
+	Load() (map[string]string, error)
+	// Save replaces the saved positions.
+	Save(positions map[string]string) error
+}
+
+// FileCheckpoints is a CheckpointStore backed by a file written with
+// state.SaveFile, so a crash never leaves a partial checkpoint.
+type FileCheckpoints string
+
+// Load reads the positions from the file. A missing file holds none.
+func (f FileCheckpoints) Load() (map[string]string, error) {
+	s := positions{}
+	if err := state.LoadFile(string(f), &s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Save writes positions to the file.
+func (f FileCheckpoints) Save(p map[string]string) error {
+	s := positions(p)
+	return state.SaveFile(string(f), &s)
+}
+
+// positions adapts a position map to state.Snapshotter.
+type positions map[string]string
+
+func (p *positions) Export(w io.Writer) error {
+	return json.NewEncoder(w).Encode(*p)
+}
+
+func (p *positions) Import(r io.Reader) error {
+	return json.NewDecoder(r).Decode(p)
+}
+
+// Checkpointer tracks how far each pipeline source has been processed and
+// persists it periodically, so that after a restart every source resumes
+// from its last checkpoint.
+//
+// Commit a position only once the value at it has been fully processed.
+// Values committed after the last save are processed again after a crash,
+// so processing is at-least-once; make it idempotent, or drop duplicates,
+// for exactly-once effects.
+type Checkpointer struct {
+	store    CheckpointStore
+	interval time.Duration
+
+	flushMu sync.Mutex // serializes saves, so an older snapshot never lands last
+
+	mu      sync.Mutex
+	current map[string]string
+	dirty   bool
+}
+
+// OpenCheckpointer loads the saved positions from store and returns a
+// Checkpointer that saves them every interval while Run is running. A
+// non-positive interval defaults to one second.
+func OpenCheckpointer(store CheckpointStore, interval time.Duration) (*Checkpointer, error) {
+	saved, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	if saved == nil {
+		saved = make(map[string]string)
+	}
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	return &Checkpointer{store: store, interval: interval, current: saved}, nil
+}
+
+// Position returns the position to resume source from, or "" to start it
+// from the beginning.
+func (c *Checkpointer) Position(source string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.current[source]
+}
+
+// Commit records that source has been processed up to position. It is saved
+// by the next Flush.
+func (c *Checkpointer) Commit(source, position string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.current[source] = position
+	c.dirty = true
+}
+
+// Flush saves the committed positions, if any changed since the last save.
+// Concurrent flushes save one after the other; commits don't wait for them.
+func (c *Checkpointer) Flush() error {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	c.mu.Lock()
+	if !c.dirty {
+		c.mu.Unlock()
+		return nil
+	}
+	snapshot := maps.Clone(c.current)
+	c.dirty = false
+	c.mu.Unlock()
+
+	if err := c.store.Save(snapshot); err != nil {
+		c.mu.Lock()
+		c.dirty = true // Try again on the next flush
+		c.mu.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+// Run flushes every interval until ctx is done, then flushes one last time.
+// Failed flushes are logged and retried on the next tick.
+func (c *Checkpointer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.Flush(); err != nil {
+				log.Printf("pipeline: saving checkpoint: %v", err)
+			}
+		case <-ctx.Done():
+			return c.Flush()
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package pipeline
+
+import (
+	"maps"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gatedStore is a CheckpointStore whose first Save blocks until released.
+// It records saves as they complete, the order in which they would land.
+type gatedStore struct {
+	mu      sync.Mutex
+	calls   int
+	saved   []map[string]string
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (s *gatedStore) Load() (map[string]string, error) { return nil, nil }
+
+func (s *gatedStore) Save(p map[string]string) error {
+	s.mu.Lock()
+	s.calls++
+	first := s.calls == 1
+	s.mu.Unlock()
+
+	if first {
+		close(s.entered)
+		<-s.release
+	}
+
+	s.mu.Lock()
+	s.saved = append(s.saved, maps.Clone(p))
+	s.mu.Unlock()
+	return nil
+}
+
+func TestCheckpointerFlushesInOrder(t *testing.T) {
+	store := &gatedStore{entered: make(chan struct{}), release: make(chan struct{})}
+	c, err := OpenCheckpointer(store, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	flush := func() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.Flush(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	c.Commit("src", "1")
+	flush()
+	<-store.entered // The first save is stuck holding position 1
+
+	c.Commit("src", "2")
+	flush()
+	time.Sleep(10 * time.Millisecond) // Give the second flush time to overtake
+	close(store.release)
+	wg.Wait()
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if last := store.saved[len(store.saved)-1]["src"]; last != "2" {
+		t.Fatalf("last save holds position %q, want the newer 2", last)
+	}
+}
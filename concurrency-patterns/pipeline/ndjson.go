@@ -0,0 +1,101 @@
+package pipeline
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// FromNDJSON returns a channel that emits the values decoded from r, which
+// holds a stream of JSON values such as newline-delimited JSON, and is closed
+// at the end of the stream. A malformed value aborts the pipeline.
+func FromNDJSON[T any](p *Pipeline, r io.Reader) <-chan T {
+	out := make(chan T)
+
+	go func() {
+		defer close(out)
+
+		dec := json.NewDecoder(r)
+		for {
+			var v T
+			if err := dec.Decode(&v); err != nil {
+				if !errors.Is(err, io.EOF) {
+					p.Abort(fmt.Errorf("decode ndjson: %w", err))
+				}
+				return
+			}
+
+			select {
+			case out <- v:
+			case <-p.ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// ToNDJSON writes every value from in to w as newline-delimited JSON, until
+// in is closed or the pipeline is aborted. Output is buffered and flushed at
+// least every flushInterval, or after every value if flushInterval is not
+// positive; an http.ResponseWriter is flushed to the client too.
+//
+// A write error aborts the pipeline. ToNDJSON returns the pipeline's error,
+// if it was aborted.
+func ToNDJSON[T any](p *Pipeline, w io.Writer, in <-chan T, flushInterval time.Duration) error {
+	buf := bufio.NewWriter(w)
+	enc := json.NewEncoder(buf)
+
+	flush := func() error {
+		if err := buf.Flush(); err != nil {
+			return err
+		}
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		return nil
+	}
+
+	var tick <-chan time.Time
+	if flushInterval > 0 {
+		ticker := time.NewTicker(flushInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case v, ok := <-in:
+			if !ok {
+				if err := flush(); err != nil {
+					p.Abort(fmt.Errorf("write ndjson: %w", err))
+				}
+				return p.Err()
+			}
+
+			err := enc.Encode(v)
+			if err == nil && tick == nil {
+				err = flush()
+			}
+			if err != nil {
+				p.Abort(fmt.Errorf("write ndjson: %w", err))
+				return p.Err()
+			}
+
+		case <-tick:
+			if err := flush(); err != nil {
+				p.Abort(fmt.Errorf("write ndjson: %w", err))
+				return p.Err()
+			}
+
+		case <-p.ctx.Done():
+			_ = flush() // Keep what was written; the pipeline already failed
+			return p.Err()
+		}
+	}
+}
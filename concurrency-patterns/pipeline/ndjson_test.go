@@ -0,0 +1,36 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestToNDJSONStopsOnAbort(t *testing.T) {
+	p := New(context.Background())
+	in := make(chan int) // Never closed
+	var buf bytes.Buffer
+
+	done := make(chan error, 1)
+	go func() {
+		done <- ToNDJSON(p, &buf, in, time.Hour)
+	}()
+
+	in <- 1
+	errBoom := errors.New("boom")
+	p.Abort(errBoom)
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, errBoom) {
+			t.Fatalf("ToNDJSON = %v, want the abort cause", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ToNDJSON blocked after the pipeline was aborted")
+	}
+	if got := buf.String(); got != "1\n" {
+		t.Fatalf("wrote %q, want the value received before the abort flushed", got)
+	}
+}
@@ -0,0 +1,285 @@
+// Package pipeline chains processing stages connected by channels, with a
+// configurable strategy for what each stage does when processing a value fails.
+//
+// Every stage reads values from an input channel, applies a function, and
+// sends results to an output channel that feeds the next stage. A Stage
+// handles errors itself: it either skips the value, reports it to an error
+// channel, retries it, or aborts the whole pipeline. A Results stage instead
+// passes every outcome downstream as a result.Result, failures carrying a
+// *StageError, and leaves them to later stages.
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/1core-dev/cloud-native/concurrency-patterns/result"
+	"github.com/1core-dev/cloud-native/stability-patterns/backoff"
+)
+
+// StageFunc processes a single value.
+type StageFunc[In, Out any] func(context.Context, In) (Out, error)
+
+// Pipeline is the shared context of a group of stages. Aborting it stops
+// every stage.
+type Pipeline struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+}
+
+// New returns a Pipeline that is stopped when ctx is done.
+func New(ctx context.Context) *Pipeline {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &Pipeline{ctx: ctx, cancel: cancel}
+}
+
+// Context returns the context passed to every stage function. It is done
+// once the pipeline is aborted.
+func (p *Pipeline) Context() context.Context {
+	return p.ctx
+}
+
+// Abort stops every stage of the pipeline, recording err as the cause.
+func (p *Pipeline) Abort(err error) {
+	p.cancel(err)
+}
+
+// Err returns why the pipeline stopped early, or nil while it is running.
+func (p *Pipeline) Err() error {
+	if p.ctx.Err() == nil {
+		return nil
+	}
+	return context.Cause(p.ctx)
+}
+
+// StageError describes a value a stage failed to process.
+type StageError struct {
+	Stage    string // name of the stage
+	Attempts int    // how many times the value was tried
+	Err      error  // error of the last attempt
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("stage %q failed after %d attempt(s): %v", e.Stage, e.Attempts, e.Err)
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// strategyKind selects what an ErrorStrategy does.
+type strategyKind int
+
+const (
+	abort strategyKind = iota
+	skip
+	sendTo
+	retry
+)
+
+// ErrorStrategy decides what a stage does when its function returns an error.
+// The zero value aborts the pipeline.
+type ErrorStrategy struct {
+	kind    strategyKind
+	errs    chan<- error
+	retries int
+	backoff backoff.Backoff
+	then    *ErrorStrategy
+}
+
+// Abort stops the whole pipeline on the first error; Pipeline.Err reports
+// the *StageError.
+func Abort() ErrorStrategy {
+	return ErrorStrategy{kind: abort}
+}
+
+// SkipAndLog logs the error and drops the value.
+func SkipAndLog() ErrorStrategy {
+	return ErrorStrategy{kind: skip}
+}
+
+// SendTo sends a *StageError to errs and drops the value. The stage blocks
+// until errs accepts the error or the pipeline stops.
+func SendTo(errs chan<- error) ErrorStrategy {
+	return ErrorStrategy{kind: sendTo, errs: errs}
+}
+
+// RetryWithPolicy re-runs the stage function up to maxRetries more times,
+// waiting b.Delay(attempt) between attempts; a nil b uses backoff.Default().
+// If every attempt fails, the error is handled by then.
+func RetryWithPolicy(maxRetries int, b backoff.Backoff, then ErrorStrategy) ErrorStrategy {
+	if b == nil {
+		b = backoff.Default()
+	}
+
+	return ErrorStrategy{kind: retry, retries: maxRetries, backoff: b, then: &then}
+}
+
+// Option configures a stage.
+type Option func(*stageOptions)
+
+type stageOptions struct {
+	name    string
+	onError ErrorStrategy
+}
+
+// WithName sets the stage name reported in errors.
+func WithName(name string) Option {
+	return func(o *stageOptions) {
+		o.name = name
+	}
+}
+
+// OnError sets the stage's error strategy. The default is Abort.
+func OnError(s ErrorStrategy) Option {
+	return func(o *stageOptions) {
+		o.onError = s
+	}
+}
+
+// Stage starts a goroutine that applies fn to every value from in and sends
+// the results to the returned channel, in order.
+//
+// The output channel is closed when in is closed or the pipeline stops.
+func Stage[In, Out any](p *Pipeline, in <-chan In, fn StageFunc[In, Out], opts ...Option) <-chan Out {
+	var o stageOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	out := make(chan Out)
+
+	go func() {
+		defer close(out)
+
+		for {
+			var (
+				v  In
+				ok bool
+			)
+
+			select {
+			case v, ok = <-in:
+				if !ok {
+					return
+				}
+			case <-p.ctx.Done():
+				return
+			}
+
+			res, ok := process(p, &o, fn, v)
+			if !ok {
+				if p.ctx.Err() != nil {
+					return
+				}
+				continue // Value dropped by the error strategy
+			}
+
+			select {
+			case out <- res:
+			case <-p.ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// Results is like Stage, but passes errors downstream instead of handling
+// them: it sends the outcome of fn for every value, failed or not, as a
+// Result whose error is a *StageError. Use it when a later stage or the
+// consumer decides what to do with failures.
+func Results[In, Out any](p *Pipeline, in <-chan In, fn StageFunc[In, Out], opts ...Option) <-chan result.Result[Out] {
+	var o stageOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return Stage(p, in, func(ctx context.Context, v In) (result.Result[Out], error) {
+		out, err := fn(ctx, v)
+		if err != nil {
+			err = &StageError{Stage: o.name, Attempts: 1, Err: err}
+		}
+		return result.Of(out, err), nil
+	}, opts...)
+}
+
+// process runs fn on v, applying the stage's error strategy. It reports
+// whether a result was produced.
+func process[In, Out any](p *Pipeline, o *stageOptions, fn StageFunc[In, Out], v In) (Out, bool) {
+	s := o.onError
+	attempts := 0 // attempts under the current strategy
+	total := 0    // attempts across all strategies
+
+	for {
+		res, err := fn(p.ctx, v)
+		attempts++
+		total++
+
+		if err == nil {
+			return res, true
+		}
+
+		// Retry strategies may wrap another retry; unwrap until one has room
+		for s.kind == retry && attempts > s.retries {
+			s = *s.then
+			attempts = 1
+		}
+
+		serr := &StageError{Stage: o.name, Attempts: total, Err: err}
+
+		switch s.kind {
+		case retry:
+			select {
+			case <-time.After(s.backoff.Delay(attempts - 1)):
+				continue
+			case <-p.ctx.Done():
+				return res, false
+			}
+		case skip:
+			log.Printf("pipeline: skipping value: %v", serr)
+		case sendTo:
+			select {
+			case s.errs <- serr:
+			case <-p.ctx.Done():
+			}
+		default:
+			p.Abort(serr)
+		}
+
+		return res, false
+	}
+}
+
+// FromSlice returns a channel that emits values and is then closed.
+func FromSlice[T any](p *Pipeline, values ...T) <-chan T {
+	out := make(chan T)
+
+	go func() {
+		defer close(out)
+
+		for _, v := range values {
+			select {
+			case out <- v:
+			case <-p.ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// Collect reads every value from in and returns them once in is closed,
+// together with the pipeline's error, if it was aborted.
+func Collect[T any](p *Pipeline, in <-chan T) ([]T, error) {
+	var res []T
+	for v := range in {
+		res = append(res, v)
+	}
+
+	return res, p.Err()
+}
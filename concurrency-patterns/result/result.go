@@ -0,0 +1,53 @@
+// Package result gives channels that carry errors one shape. Instead of each
+// pattern defining its own struct of a value and an error, they send a
+// Result, and the helpers here transform and combine them.
+package result
+
+// Result is the outcome of an operation: a value, or the error that
+// prevented it.
+type Result[T any] struct {
+	Value T
+	Err   error
+}
+
+// Of returns the Result of an operation that returned v and err.
+func Of[T any](v T, err error) Result[T] {
+	return Result[T]{Value: v, Err: err}
+}
+
+// Ok returns a successful Result holding v.
+func Ok[T any](v T) Result[T] {
+	return Result[T]{Value: v}
+}
+
+// Fail returns a failed Result holding err.
+func Fail[T any](err error) Result[T] {
+	return Result[T]{Err: err}
+}
+
+// Unwrap returns the value and error held by r.
+func (r Result[T]) Unwrap() (T, error) {
+	return r.Value, r.Err
+}
+
+// Map applies fn to the value of a successful r. A failed r is passed
+// through with its error.
+func Map[T, U any](r Result[T], fn func(T) U) Result[U] {
+	if r.Err != nil {
+		return Fail[U](r.Err)
+	}
+	return Ok(fn(r.Value))
+}
+
+// Collect combines rs into a single Result holding every value in order, or
+// the first error among them.
+func Collect[T any](rs []Result[T]) Result[[]T] {
+	values := make([]T, len(rs))
+	for i, r := range rs {
+		if r.Err != nil {
+			return Fail[[]T](r.Err)
+		}
+		values[i] = r.Value
+	}
+	return Ok(values)
+}
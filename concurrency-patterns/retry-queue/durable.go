@@ -0,0 +1,382 @@
+package retryqueue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/1core-dev/cloud-native/manageability-patterns/envelope"
+	"github.com/1core-dev/cloud-native/stability-patterns/backoff"
+)
+
+// Record is a failed operation persisted for a later retry.
+type Record[T any] struct {
+	ID        string    `json:"id"`
+	Payload   T         `json:"payload"`
+	Attempts  int       `json:"attempts"` // failures so far
+	Due       time.Time `json:"due"`      // when to retry next
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// Store persists the records of an Executor, so pending retries survive a
+// restart. Implementations must be safe for concurrent use. RedisStore
+// keeps records in Redis; a SQL store can be plugged in by implementing it.
+type Store[T any] interface {
+	// Put inserts the record, or replaces the one with the same ID.
+	Put(r Record[T]) error
+	// Delete removes the record with the given ID, if any.
+	Delete(id string) error
+	// Due returns up to limit records due by now, earliest first.
+	Due(now time.Time, limit int) ([]Record[T], error)
+}
+
+// MemoryStore is a Store that keeps records in memory. It does not survive
+// restarts, but suits tests and services that only need the scheduling.
+type MemoryStore[T any] struct {
+	mu      sync.Mutex
+	records map[string]Record[T]
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore[T any]() *MemoryStore[T] {
+	return &MemoryStore[T]{records: make(map[string]Record[T])}
+}
+
+// Put stores r.
+func (s *MemoryStore[T]) Put(r Record[T]) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[r.ID] = r
+	return nil
+}
+
+// Delete removes the record with the given ID.
+func (s *MemoryStore[T]) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, id)
+	return nil
+}
+
+// Due returns up to limit records due by now, earliest first.
+func (s *MemoryStore[T]) Due(now time.Time, limit int) ([]Record[T], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return due(s.records, now, limit), nil
+}
+
+// FileStore is a Store that keeps every record in one file, rewritten in a
+// checksummed envelope after each change. It suits modest volumes of
+// pending retries.
+type FileStore[T any] struct {
+	path string
+
+	mu      sync.Mutex
+	records map[string]Record[T] // nil until loaded
+}
+
+// NewFileStore returns a FileStore persisting to path. Records already in
+// the file are picked up on first use.
+func NewFileStore[T any](path string) *FileStore[T] {
+	return &FileStore[T]{path: path}
+}
+
+// Put stores r and rewrites the file.
+func (s *FileStore[T]) Put(r Record[T]) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	s.records[r.ID] = r
+	return s.save()
+}
+
+// Delete removes the record with the given ID and rewrites the file.
+func (s *FileStore[T]) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	if _, ok := s.records[id]; !ok {
+		return nil
+	}
+	delete(s.records, id)
+	return s.save()
+}
+
+// Due returns up to limit records due by now, earliest first.
+func (s *FileStore[T]) Due(now time.Time, limit int) ([]Record[T], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return due(s.records, now, limit), nil
+}
+
+// load reads the file once. A missing file holds no records. Callers must
+// hold s.mu.
+func (s *FileStore[T]) load() error {
+	if s.records != nil {
+		return nil
+	}
+
+	records := make(map[string]Record[T])
+
+	f, err := os.Open(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		s.records = records
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	data, err := envelope.Decode(f, 0)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &records); err != nil {
+		return err
+	}
+
+	s.records = records
+	return nil
+}
+
+// save replaces the file with the records atomically and durably; see
+// envelope.WriteFile. Callers must hold s.mu.
+func (s *FileStore[T]) save() error {
+	data, err := json.Marshal(s.records)
+	if err != nil {
+		return err
+	}
+
+	return envelope.WriteFile(s.path, data, true)
+}
+
+// due returns up to limit of records due by now, earliest first.
+func due[T any](records map[string]Record[T], now time.Time, limit int) []Record[T] {
+	var res []Record[T]
+	for _, r := range records {
+		if !r.Due.After(now) {
+			res = append(res, r)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Due.Before(res[j].Due) })
+	if limit > 0 && len(res) > limit {
+		res = res[:limit]
+	}
+	return res
+}
+
+// Executor retries failed operations durably, outbox-style: Enqueue persists
+// an operation's payload in a Store, and Run executes it again when it is
+// due, until it succeeds or reaches MaxAttempts. Pending retries survive a
+// restart as long as the Store does.
+//
+// As with a Queue, Interval limits the rate of re-submissions. PollInterval
+// is how often Run looks for due records in the store.
+type Executor[T any] struct {
+	store  Store[T]
+	handle func(context.Context, T) error
+	cfg    Config
+
+	mu    sync.Mutex
+	stats Stats
+	wake  chan struct{}
+}
+
+// NewExecutor returns an Executor that persists retries in store and runs
+// them with handle.
+func NewExecutor[T any](store Store[T], handle func(context.Context, T) error, cfg Config) *Executor[T] {
+	if cfg.Backoff == nil {
+		cfg.Backoff = backoff.Default()
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+
+	return &Executor[T]{store: store, handle: handle, cfg: cfg, wake: make(chan struct{}, 1)}
+}
+
+// Enqueue persists payload, whose first execution failed with err, for a
+// retry after the first backoff delay. It returns the record's ID, or an
+// empty one if MaxAttempts is 1: that failure was the last allowed, so the
+// payload is dropped instead, as Queue.Requeue does.
+func (e *Executor[T]) Enqueue(payload T, err error) (string, error) {
+	if e.cfg.MaxAttempts == 1 {
+		e.mu.Lock()
+		e.stats.Dropped++
+		e.mu.Unlock()
+		return "", nil
+	}
+
+	var buf [16]byte
+	rand.Read(buf[:])
+
+	r := Record[T]{
+		ID:       hex.EncodeToString(buf[:]),
+		Payload:  payload,
+		Attempts: 1,
+		Due:      time.Now().Add(e.cfg.Backoff.Delay(0)),
+	}
+	if err != nil {
+		r.LastError = err.Error()
+	}
+
+	if err := e.store.Put(r); err != nil {
+		return "", err
+	}
+
+	e.mu.Lock()
+	e.stats.Requeued++
+	e.mu.Unlock()
+
+	select {
+	case e.wake <- struct{}{}:
+	default: // Run was already signalled
+	}
+
+	return r.ID, nil
+}
+
+// Stats returns the executor's counters since it was created. Depth is not
+// tracked, since the store may be shared with other processes.
+func (e *Executor[T]) Stats() Stats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.stats
+}
+
+// Run executes due records until ctx is done. Store errors are logged and
+// retried on the next poll.
+func (e *Executor[T]) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.cfg.PollInterval)
+	defer ticker.Stop()
+
+	var last time.Time // when the last record was re-submitted
+	for {
+		e.drain(ctx, &last)
+
+		select {
+		case <-ticker.C:
+		case <-e.wake:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// drain executes the records due now, a batch at a time, at most one every
+// Interval since *last. It stops at the first store error, since records
+// whose outcome could not be stored are still due and would otherwise be run
+// again at once.
+func (e *Executor[T]) drain(ctx context.Context, last *time.Time) {
+	const batch = 64
+
+	for ctx.Err() == nil {
+		records, err := e.store.Due(time.Now(), batch)
+		if err != nil {
+			slog.WarnContext(ctx, "retry queue: load due records failed", "error", err)
+			return
+		}
+
+		for _, r := range records {
+			if !e.pace(ctx, *last) {
+				return
+			}
+			*last = time.Now()
+			if !e.execute(ctx, r) {
+				return // The store is failing: let the next poll retry
+			}
+		}
+
+		if len(records) < batch {
+			return
+		}
+	}
+}
+
+// pace waits until Interval has passed since last, the previous
+// re-submission. It reports false if ctx was done first.
+func (e *Executor[T]) pace(ctx context.Context, last time.Time) bool {
+	wait := time.Until(last.Add(e.cfg.Interval))
+	if wait <= 0 {
+		return ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// execute runs one record and stores its outcome. It reports whether the
+// outcome was stored.
+func (e *Executor[T]) execute(ctx context.Context, r Record[T]) bool {
+	err := e.handle(ctx, r.Payload)
+
+	e.mu.Lock()
+	e.stats.Resubmitted++
+	e.mu.Unlock()
+
+	if err == nil {
+		if err := e.store.Delete(r.ID); err != nil {
+			slog.WarnContext(ctx, "retry queue: delete record failed", "id", r.ID, "error", err)
+			return false
+		}
+		return true
+	}
+
+	r.Attempts++
+	r.LastError = err.Error()
+
+	if e.cfg.MaxAttempts > 0 && r.Attempts >= e.cfg.MaxAttempts {
+		e.mu.Lock()
+		e.stats.Dropped++
+		e.mu.Unlock()
+
+		slog.WarnContext(ctx, "retry queue: dropping record", "id", r.ID, "attempts", r.Attempts, "error", err)
+		if err := e.store.Delete(r.ID); err != nil {
+			slog.WarnContext(ctx, "retry queue: delete record failed", "id", r.ID, "error", err)
+			return false
+		}
+		return true
+	}
+
+	e.mu.Lock()
+	e.stats.Requeued++
+	e.mu.Unlock()
+
+	r.Due = time.Now().Add(e.cfg.Backoff.Delay(r.Attempts - 1))
+	if err := e.store.Put(r); err != nil {
+		slog.WarnContext(ctx, "retry queue: store record failed", "id", r.ID, "error", err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package retryqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/1core-dev/cloud-native/stability-patterns/backoff"
+)
+
+var errFailed = errors.New("failed")
+
+func TestExecutorEnqueueDropsAtMaxAttempts(t *testing.T) {
+	store := NewMemoryStore[string]()
+	e := NewExecutor(store, func(context.Context, string) error { return nil }, Config{MaxAttempts: 1})
+
+	if id, err := e.Enqueue("job", errFailed); id != "" || err != nil {
+		t.Fatalf("Enqueue = %q, %v; want the payload dropped", id, err)
+	}
+	if due, _ := store.Due(time.Now().Add(time.Hour), 0); len(due) != 0 {
+		t.Fatalf("%d records stored, want none", len(due))
+	}
+	if s := e.Stats(); s.Dropped != 1 || s.Requeued != 0 {
+		t.Fatalf("Stats = %+v, want one drop", s)
+	}
+}
+
+func TestExecutorPacesResubmissions(t *testing.T) {
+	const interval = 50 * time.Millisecond
+
+	ran := make(chan time.Time, 3)
+	e := NewExecutor(NewMemoryStore[string](), func(context.Context, string) error {
+		ran <- time.Now()
+		return nil
+	}, Config{Backoff: backoff.Constant{}, Interval: interval, PollInterval: time.Hour})
+
+	for range 3 {
+		if _, err := e.Enqueue("job", errFailed); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go e.Run(ctx)
+
+	prev := <-ran
+	for range 2 {
+		at := <-ran
+		if gap := at.Sub(prev); gap < interval {
+			t.Fatalf("re-submissions %v apart, want at least %v", gap, interval)
+		}
+		prev = at
+	}
+}
@@ -0,0 +1,122 @@
+package retryqueue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Evaluator is the one Redis command RedisStore needs: EVAL of a Lua
+// script with its keys and arguments, returning the script's reply. The
+// Eval method of a go-redis client, followed by Result, fits it.
+type Evaluator interface {
+	Eval(ctx context.Context, script string, keys []string, args ...any) (any, error)
+}
+
+// Scripts of RedisStore. Records are JSON values in a hash keyed by ID, and
+// a sorted set orders their IDs by due time, in Unix milliseconds. Each
+// script updates both keys in one atomic step.
+const (
+	putScript = `
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+redis.call('ZADD', KEYS[2], ARGV[3], ARGV[1])
+return 1
+`
+
+	deleteScript = `
+redis.call('HDEL', KEYS[1], ARGV[1])
+redis.call('ZREM', KEYS[2], ARGV[1])
+return 1
+`
+
+	dueScript = `
+local ids = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+if #ids == 0 then
+	return {}
+end
+return redis.call('HMGET', KEYS[1], unpack(ids))
+`
+)
+
+// RedisStore is a Store that keeps records in Redis, so pending retries
+// survive a restart and can be shared by the replicas of a service. Since
+// Store methods take no context, calls to Redis use context.Background;
+// bound them with the client's own timeouts.
+//
+// Replicas sharing a store may pick up the same due record at once, so
+// handlers must be idempotent, as they must be for any retry.
+type RedisStore[T any] struct {
+	eval   Evaluator
+	prefix string
+}
+
+// NewRedisStore returns a RedisStore that runs its scripts through eval and
+// keeps its records under the keys prefix+"records" and prefix+"due".
+func NewRedisStore[T any](eval Evaluator, prefix string) *RedisStore[T] {
+	return &RedisStore[T]{eval: eval, prefix: prefix}
+}
+
+// Put stores r.
+func (s *RedisStore[T]) Put(r Record[T]) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.eval.Eval(context.Background(), putScript, s.keys(), r.ID, data, r.Due.UnixMilli())
+	return err
+}
+
+// Delete removes the record with the given ID.
+func (s *RedisStore[T]) Delete(id string) error {
+	_, err := s.eval.Eval(context.Background(), deleteScript, s.keys(), id)
+	return err
+}
+
+// Due returns up to limit records due by now, earliest first.
+func (s *RedisStore[T]) Due(now time.Time, limit int) ([]Record[T], error) {
+	if limit <= 0 {
+		limit = -1 // No limit
+	}
+
+	reply, err := s.eval.Eval(context.Background(), dueScript, s.keys(),
+		strconv.FormatInt(now.UnixMilli(), 10), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	values, ok := reply.([]any)
+	if !ok {
+		return nil, fmt.Errorf("retry queue: unexpected script reply %T", reply)
+	}
+
+	records := make([]Record[T], 0, len(values))
+	for _, v := range values {
+		var data []byte
+		switch v := v.(type) {
+		case nil:
+			continue // Deleted since it was listed
+		case string:
+			data = []byte(v)
+		case []byte:
+			data = v
+		default:
+			return nil, fmt.Errorf("retry queue: unexpected record reply %T", v)
+		}
+
+		var r Record[T]
+		if err := json.Unmarshal(data, &r); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+
+	return records, nil
+}
+
+// keys returns the Redis keys of the store.
+func (s *RedisStore[T]) keys() []string {
+	return []string{s.prefix + "records", s.prefix + "due"}
+}
@@ -0,0 +1,170 @@
+// Package retryqueue captures failed tasks, such as jobs from a worker pool,
+// and re-submits them later.
+//
+// Each failed task waits in a delay queue for a backoff that grows with its
+// number of attempts, and is dropped once it reaches the maximum. Due tasks
+// are re-submitted at a limited rate, so a burst of failures doesn't turn
+// into a burst of retries.
+//
+// Queue keeps tasks in memory. Executor persists them in a Store instead, so
+// pending retries survive a restart: a FileStore on local disk, or a
+// RedisStore shared by the replicas of a service.
+package retryqueue
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/1core-dev/cloud-native/stability-patterns/backoff"
+)
+
+// Item is a task together with how many times it has failed.
+type Item[T any] struct {
+	Task     T
+	Attempts int
+}
+
+// Config configures a Queue or an Executor.
+type Config struct {
+	MaxAttempts  int             // failures after which a task is dropped; 0 means no limit
+	Backoff      backoff.Backoff // delay before each retry; nil uses backoff.Default()
+	Interval     time.Duration   // minimum time between two re-submissions
+	PollInterval time.Duration   // how often an Executor polls its store; 0 means one second
+}
+
+// Stats is a snapshot of the queue's metrics.
+type Stats struct {
+	Depth       int    // tasks currently waiting
+	Requeued    uint64 // failures accepted for retry
+	Resubmitted uint64 // tasks handed back to submit
+	Dropped     uint64 // tasks that reached MaxAttempts
+}
+
+// Queue holds failed tasks until they are due for another attempt.
+type Queue[T any] struct {
+	submit func(Item[T])
+	cfg    Config
+
+	mu    sync.Mutex
+	items delayHeap[T]
+	stats Stats
+	wake  chan struct{} // signals Run that a new item was queued
+}
+
+// New returns a Queue that hands due tasks to submit. submit receives the
+// Item so its Attempts can be passed back to Requeue if it fails again.
+func New[T any](submit func(Item[T]), cfg Config) *Queue[T] {
+	if cfg.Backoff == nil {
+		cfg.Backoff = backoff.Default()
+	}
+
+	return &Queue[T]{submit: submit, cfg: cfg, wake: make(chan struct{}, 1)}
+}
+
+// Requeue records a failure of it and schedules it for another attempt. It
+// returns false if the task reached MaxAttempts and was dropped instead.
+func (q *Queue[T]) Requeue(it Item[T]) bool {
+	it.Attempts++
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.cfg.MaxAttempts > 0 && it.Attempts >= q.cfg.MaxAttempts {
+		q.stats.Dropped++
+		return false
+	}
+
+	due := time.Now().Add(q.cfg.Backoff.Delay(it.Attempts - 1))
+	heap.Push(&q.items, delayed[T]{item: it, due: due})
+	q.stats.Requeued++
+
+	select {
+	case q.wake <- struct{}{}:
+	default: // Run was already signalled
+	}
+
+	return true
+}
+
+// Stats returns the queue's current metrics.
+func (q *Queue[T]) Stats() Stats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	s := q.stats
+	s.Depth = len(q.items)
+	return s
+}
+
+// Run re-submits tasks as they become due until ctx is done. Tasks still
+// waiting when Run returns stay queued.
+func (q *Queue[T]) Run(ctx context.Context) {
+	var last time.Time // when the last task was re-submitted
+
+	for {
+		q.mu.Lock()
+		wait := time.Duration(-1) // Nothing queued: wait for a wake-up
+		if len(q.items) > 0 {
+			due := q.items[0].due
+			if next := last.Add(q.cfg.Interval); next.After(due) {
+				due = next // Respect the re-submission rate
+			}
+			wait = time.Until(due)
+		}
+
+		if len(q.items) > 0 && wait <= 0 {
+			it := heap.Pop(&q.items).(delayed[T]).item
+			q.stats.Resubmitted++
+			q.mu.Unlock()
+
+			last = time.Now()
+			q.submit(it)
+			continue
+		}
+		q.mu.Unlock()
+
+		var timer *time.Timer
+		var fire <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			fire = timer.C
+		}
+
+		select {
+		case <-fire:
+		case <-q.wake:
+		case <-ctx.Done():
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// delayed is a queued item and when it is due.
+type delayed[T any] struct {
+	item Item[T]
+	due  time.Time
+}
+
+// delayHeap orders delayed items by due time, earliest first.
+type delayHeap[T any] []delayed[T]
+
+func (h delayHeap[T]) Len() int           { return len(h) }
+func (h delayHeap[T]) Less(i, j int) bool { return h[i].due.Before(h[j].due) }
+func (h delayHeap[T]) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *delayHeap[T]) Push(x any)        { *h = append(*h, x.(delayed[T])) }
+
+func (h *delayHeap[T]) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
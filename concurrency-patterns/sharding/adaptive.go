@@ -0,0 +1,288 @@
+package sharding
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TunerConfig bounds and paces the shard-count tuner of an AdaptiveMap.
+type TunerConfig struct {
+	MinShards int           // fewest shards; defaults to 1
+	MaxShards int           // most shards; defaults to 64 times MinShards
+	Interval  time.Duration // time between samples; defaults to 10s
+
+	// Grow doubles the shard count when more than this fraction of lock
+	// acquisitions had to wait; defaults to 0.05. Shrink halves it when
+	// fewer than this fraction did; defaults to 0.005.
+	Grow, Shrink float64
+}
+
+// minSampleOps is the fewest lock acquisitions in a sample worth acting on.
+const minSampleOps = 1000
+
+// TunerStats describes the last sample taken by the tuner.
+type TunerStats struct {
+	Shards     int     // current shard count
+	Ops        uint64  // lock acquisitions during the sample
+	Contention float64 // fraction of Ops that had to wait
+	Hottest    float64 // share of the waits taken by the busiest shard
+}
+
+// AdaptiveMap is a sharded map whose shard count can change while it is in
+// use. Resize migrates it one shard at a time, so only the keys of the
+// shard being moved are blocked; Run tunes the count from measured lock
+// contention, so it doesn't have to be guessed up front.
+type AdaptiveMap[K comparable, V any] struct {
+	opts []Option
+	cfg  TunerConfig
+
+	resize sync.Mutex // held for the duration of a resize, and by Keys
+	table  atomic.Pointer[table[K, V]]
+
+	statsMu sync.Mutex
+	stats   TunerStats
+}
+
+// table is the current shards of an AdaptiveMap and, during a resize, the
+// shards being migrated to.
+type table[K comparable, V any] struct {
+	cur  ShardedMap[K, V]
+	next ShardedMap[K, V] // nil unless resizing
+}
+
+// NewAdaptiveMap returns an AdaptiveMap starting with cfg.MinShards shards.
+// opts configure the shards as for NewShardedMap.
+func NewAdaptiveMap[K comparable, V any](cfg TunerConfig, opts ...Option) *AdaptiveMap[K, V] {
+	if cfg.MinShards < 1 {
+		cfg.MinShards = 1
+	}
+	if cfg.MaxShards < cfg.MinShards {
+		cfg.MaxShards = 64 * cfg.MinShards
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10 * time.Second
+	}
+	if cfg.Grow <= 0 {
+		cfg.Grow = 0.05
+	}
+	if cfg.Shrink <= 0 {
+		cfg.Shrink = 0.005
+	}
+
+	m := &AdaptiveMap[K, V]{opts: opts, cfg: cfg}
+	m.table.Store(&table[K, V]{cur: NewShardedMap[K, V](cfg.MinShards, opts...)})
+	m.stats.Shards = cfg.MinShards
+
+	return m
+}
+
+// Get retrieves the value associated with the given key.
+func (m *AdaptiveMap[K, V]) Get(key K) V {
+	for {
+		t := m.table.Load()
+		for _, sm := range [...]ShardedMap[K, V]{t.cur, t.next} {
+			if sm == nil {
+				continue
+			}
+			shard := sm.getShard(key)
+
+			if shard.lockFree() {
+				v := shard.loadFree(key)
+				if !shard.moved.Load() { // Checked after loading: see migrate
+					return v
+				}
+				continue
+			}
+
+			shard.ops.Add(1)
+			if !shard.TryRLock() {
+				shard.contended.Add(1)
+				shard.RLock()
+			}
+			if !shard.moved.Load() {
+				v := shard.items[key]
+				shard.RUnlock()
+				return v
+			}
+			shard.RUnlock()
+		}
+		// Resized again in the meantime: start over from the new table
+	}
+}
+
+// Set inserts or updates the value associated with the given key.
+func (m *AdaptiveMap[K, V]) Set(key K, value V) {
+	shard := m.lock(key)
+	defer shard.Unlock()
+
+	shard.storeOne(key, value)
+}
+
+// lock returns the shard currently holding key, write-locked.
+func (m *AdaptiveMap[K, V]) lock(key K) *Shard[K, V] {
+	for {
+		t := m.table.Load()
+		for _, sm := range [...]ShardedMap[K, V]{t.cur, t.next} {
+			if sm == nil {
+				continue
+			}
+			shard := sm.getShard(key)
+
+			shard.ops.Add(1)
+			if !shard.TryLock() {
+				shard.contended.Add(1)
+				shard.Lock()
+			}
+			if !shard.moved.Load() {
+				return shard
+			}
+			shard.Unlock()
+		}
+	}
+}
+
+// Keys returns all keys of the map. It waits for a resize in progress.
+func (m *AdaptiveMap[K, V]) Keys() []K {
+	m.resize.Lock()
+	defer m.resize.Unlock()
+
+	return m.table.Load().cur.Keys()
+}
+
+// Shards returns the current shard count.
+func (m *AdaptiveMap[K, V]) Shards() int {
+	m.resize.Lock()
+	defer m.resize.Unlock()
+
+	return len(m.table.Load().cur)
+}
+
+// Resize migrates the map to n shards, one shard at a time. Reads and
+// writes carry on meanwhile, except for the keys of the shard being moved.
+func (m *AdaptiveMap[K, V]) Resize(n int) {
+	if n < 1 {
+		n = 1
+	}
+
+	m.resize.Lock()
+	defer m.resize.Unlock()
+
+	t := m.table.Load()
+	if len(t.cur) == n {
+		return
+	}
+
+	next := NewShardedMap[K, V](n, m.opts...)
+	m.table.Store(&table[K, V]{cur: t.cur, next: next})
+
+	for _, shard := range t.cur {
+		migrate(shard, next)
+		runtime.Gosched() // Let blocked callers in between shards
+	}
+
+	m.table.Store(&table[K, V]{cur: next})
+}
+
+// migrate moves the items of shard into next, then marks it moved so that
+// callers look their keys up in next.
+//
+// Lock-free readers load their value before checking moved. The flag
+// is only set once every item is in next, and writers only use next once
+// they see it set, so a reader that finds it unset reads a current value.
+func migrate[K comparable, V any](shard *Shard[K, V], next ShardedMap[K, V]) {
+	shard.Lock()
+	defer shard.Unlock()
+
+	batches := make(map[int]map[K]V)
+	for k, v := range shard.items {
+		i := next.getShardIndex(k)
+		if batches[i] == nil {
+			batches[i] = make(map[K]V)
+		}
+		batches[i][k] = v
+	}
+
+	for i, batch := range batches {
+		dst := next[i]
+		dst.Lock()
+		dst.store(batch)
+		dst.Unlock()
+	}
+
+	shard.moved.Store(true)
+	shard.publish(make(map[K]V)) // Release the moved items
+}
+
+// Stats returns the last sample taken by Run.
+func (m *AdaptiveMap[K, V]) Stats() TunerStats {
+	m.statsMu.Lock()
+	defer m.statsMu.Unlock()
+
+	return m.stats
+}
+
+// Run samples lock contention every cfg.Interval until ctx is done, and
+// doubles or halves the shard count within the configured bounds when it
+// crosses the Grow or Shrink threshold. Contention concentrated on one
+// shard, such as a single hot key, is not helped by more shards and does
+// not trigger growth.
+func (m *AdaptiveMap[K, V]) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		s := m.sample()
+
+		m.statsMu.Lock()
+		m.stats = s
+		m.statsMu.Unlock()
+
+		if s.Ops < minSampleOps {
+			continue // Too quiet to judge
+		}
+
+		hot := s.Shards > 2 && s.Hottest > 0.5 // One shard takes most waits
+
+		switch {
+		case s.Contention > m.cfg.Grow && !hot && s.Shards < m.cfg.MaxShards:
+			m.Resize(min(2*s.Shards, m.cfg.MaxShards))
+		case s.Contention < m.cfg.Shrink && s.Shards > m.cfg.MinShards:
+			m.Resize(max(s.Shards/2, m.cfg.MinShards))
+		}
+	}
+}
+
+// sample collects and resets the lock counters of the current shards.
+func (m *AdaptiveMap[K, V]) sample() TunerStats {
+	m.resize.Lock()
+	defer m.resize.Unlock()
+
+	shards := m.table.Load().cur
+	s := TunerStats{Shards: len(shards)}
+
+	var contended, hottest uint64
+	for _, shard := range shards {
+		s.Ops += shard.ops.Swap(0)
+		c := shard.contended.Swap(0)
+		contended += c
+		hottest = max(hottest, c)
+	}
+
+	if s.Ops > 0 {
+		s.Contention = float64(contended) / float64(s.Ops)
+	}
+	if contended > 0 {
+		s.Hottest = float64(hottest) / float64(contended)
+	}
+
+	return s
+}
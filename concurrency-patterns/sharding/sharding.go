@@ -17,15 +17,154 @@ package sharding
 import (
 	"fmt"
 	"hash/fnv"
+	"maps"
 	"reflect"
 	"sync"
+	"sync/atomic"
 )
 
+// ReadPath selects how the shards of a ShardedMap serve reads.
+type ReadPath int
+
+const (
+	// Locked reads under the shard's read lock. It suits most workloads.
+	Locked ReadPath = iota
+
+	// CopyOnWrite reads an immutable snapshot of the shard without taking
+	// any lock, and every write copies the shard. It removes read-side
+	// contention entirely for read-mostly workloads on many cores, at the
+	// cost of O(shard size) writes; prefer it only when reads outnumber
+	// writes by orders of magnitude.
+	CopyOnWrite
+
+	// SyncMap reads from a sync.Map mirroring the shard, without taking any
+	// lock. Writes still take the shard's lock and update both the shard and
+	// the mirror, in O(1), so it suits read-heavy workloads whose writes are
+	// too frequent for CopyOnWrite, at the cost of holding every item twice.
+	SyncMap
+)
+
+// ReadPathFor returns a read path for a workload doing readsPerWrite reads
+// for every write: Locked unless reads dominate, SyncMap for read-heavy
+// workloads, and CopyOnWrite when writes are rare enough that copying a
+// shard on each one doesn't matter.
+//
+// The lock-free paths only pay off once read locks contend, on many cores;
+// on few cores Locked is as fast or faster. Treat the thresholds as starting
+// points and confirm them with BenchmarkShardedMap on the target hardware,
+// with -cpu set to its core count.
+func ReadPathFor(readsPerWrite float64) ReadPath {
+	switch {
+	case readsPerWrite >= 1000:
+		return CopyOnWrite
+	case readsPerWrite >= 10:
+		return SyncMap
+	default:
+		return Locked
+	}
+}
+
+// Option configures optional ShardedMap behavior.
+type Option func(*options)
+
+type options struct {
+	readPath ReadPath
+}
+
+// WithReadPath sets how shards serve reads. The default is Locked.
+func WithReadPath(p ReadPath) Option {
+	return func(o *options) {
+		o.readPath = p
+	}
+}
+
 // Shard represents a single partition of a ShardedMap.
 // Each shard is an independent, lock-protected map that stores a subset of keys.
 type Shard[K comparable, V any] struct {
 	sync.RWMutex         // compose from sync.RWMutex
 	items        map[K]V // contains the shard's data
+
+	copyOnWrite bool                    // serve reads from snapshot
+	snapshot    atomic.Pointer[map[K]V] // published copy of items
+
+	syncMap bool     // serve reads from mirror
+	mirror  sync.Map // copy of items, of K to V
+
+	// Used by AdaptiveMap only
+	moved     atomic.Bool   // items migrated to another table
+	ops       atomic.Uint64 // lock acquisitions since the last sample
+	contended atomic.Uint64 // acquisitions that had to wait
+}
+
+// publish replaces the shard's data with items, which must no longer be
+// modified if the shard is copy-on-write. Callers must hold the write lock.
+func (s *Shard[K, V]) publish(items map[K]V) {
+	s.items = items
+	switch {
+	case s.copyOnWrite:
+		s.snapshot.Store(&items)
+	case s.syncMap:
+		// Refill before pruning, so that lock-free readers never miss a key
+		// present both before and after
+		for k, v := range items {
+			s.mirror.Store(k, v)
+		}
+		s.mirror.Range(func(k, _ any) bool {
+			if _, ok := items[k.(K)]; !ok {
+				s.mirror.Delete(k)
+			}
+			return true
+		})
+	}
+}
+
+// lockFree reports whether reads of the shard take no lock.
+func (s *Shard[K, V]) lockFree() bool {
+	return s.copyOnWrite || s.syncMap
+}
+
+// loadFree reads key without locking. The shard must be lockFree.
+func (s *Shard[K, V]) loadFree(key K) V {
+	if s.copyOnWrite {
+		return (*s.snapshot.Load())[key]
+	}
+
+	v, _ := s.mirror.Load(key)
+	value, _ := v.(V) // Zero if missing; also copes with a nil interface V
+	return value
+}
+
+// store sets the values of batch in the shard. Callers must hold the write
+// lock.
+func (s *Shard[K, V]) store(batch map[K]V) {
+	if s.copyOnWrite {
+		items := maps.Clone(s.items)
+		maps.Copy(items, batch)
+		s.publish(items)
+		return
+	}
+
+	maps.Copy(s.items, batch)
+	if s.syncMap {
+		for k, v := range batch {
+			s.mirror.Store(k, v)
+		}
+	}
+}
+
+// storeOne is store for a single value, without building a batch.
+func (s *Shard[K, V]) storeOne(key K, value V) {
+	if s.copyOnWrite {
+		items := maps.Clone(s.items)
+		items[key] = value
+		s.publish(items)
+		return
+	}
+
+	s.items[key] = value
+	if s.syncMap {
+		s.mirror.Store(key, value)
+	}
 }
 
 // ShardedMap is a map abstraction composed of multiple shards.
@@ -34,22 +173,33 @@ type ShardedMap[K comparable, V any] []*Shard[K, V]
 
 // NewShardedMap creates and returns a ShardedMap with the specified number of shards.
 // Each shard is initialized and protected with its own read-write mutex.
-func NewShardedMap[K comparable, V any](nshards int) ShardedMap[K, V] {
+func NewShardedMap[K comparable, V any](nshards int, opts ...Option) ShardedMap[K, V] {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	shards := make([]*Shard[K, V], nshards) // Initialize a *Shards slice
 
 	// for i := 0; i < nshards; i++ {
 	for i := range nshards {
-		shard := make(map[K]V)
-		shards[i] = &Shard[K, V]{items: shard} // A ShardedMap is a slice
+		shard := &Shard[K, V]{copyOnWrite: o.readPath == CopyOnWrite, syncMap: o.readPath == SyncMap}
+		shard.publish(make(map[K]V))
+		shards[i] = shard // A ShardedMap is a slice
 	}
 
 	return shards
 }
 
 // Get retrieves the value associated with the given key.
-// A read lock is acquired on the appropriate shard.
+// A read lock is acquired on the appropriate shard, unless its read path is
+// lock-free.
 func (m ShardedMap[K, V]) Get(key K) V {
 	shard := m.getShard(key)
+	if shard.lockFree() {
+		return shard.loadFree(key)
+	}
+
 	shard.RLock()
 	defer shard.RUnlock()
 
@@ -63,7 +213,7 @@ func (m ShardedMap[K, V]) Set(key K, value V) {
 	shard.Lock()
 	defer shard.Unlock()
 
-	shard.items[key] = value
+	shard.storeOne(key, value)
 }
 
 // Keys returns all keys from all shards as a single slice.
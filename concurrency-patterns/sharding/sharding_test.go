@@ -0,0 +1,91 @@
+package sharding
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+// BenchmarkShardedMap compares the read paths under parallel load at several
+// read/write ratios, on a map of 1024 keys over 16 shards. Run it with -cpu
+// set to the target's core count: read-lock contention, which the lock-free
+// paths remove, only shows on many cores.
+func BenchmarkShardedMap(b *testing.B) {
+	const nkeys = 1024
+
+	keys := make([]string, nkeys)
+	for i := range keys {
+		keys[i] = "key" + strconv.Itoa(i)
+	}
+
+	paths := []struct {
+		name string
+		path ReadPath
+	}{{"Locked", Locked}, {"CopyOnWrite", CopyOnWrite}, {"SyncMap", SyncMap}}
+
+	for _, readsPerWrite := range []int{1, 10, 100, 1000} {
+		for _, p := range paths {
+			b.Run(fmt.Sprintf("reads=%d/%s", readsPerWrite, p.name), func(b *testing.B) {
+				m := NewShardedMap[string, int](16, WithReadPath(p.path))
+				for i, k := range keys {
+					m.Set(k, i)
+				}
+
+				var seq atomic.Uint64
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					i := int(seq.Add(1)) * 7919 // Spread goroutines over the keys
+					for pb.Next() {
+						k := keys[i%nkeys]
+						if i%(readsPerWrite+1) == 0 {
+							m.Set(k, i)
+						} else {
+							m.Get(k)
+						}
+						i++
+					}
+				})
+			})
+		}
+	}
+}
+
+func TestSyncMapImportKeepsKeysVisible(t *testing.T) {
+	m := NewShardedMap[string, int](1, WithReadPath(SyncMap))
+	for i := range 1000 {
+		m.Set("key"+strconv.Itoa(i), i)
+	}
+	var snap bytes.Buffer
+	if err := m.Export(&snap); err != nil {
+		t.Fatal(err)
+	}
+	m.Set("gone", -1)
+
+	var stop atomic.Bool
+	missed := make(chan bool, 1)
+	go func() { // Reads without locks while Import rewrites the shard
+		miss := false
+		for !stop.Load() {
+			miss = miss || m.Get("key999") != 999
+		}
+		missed <- miss
+	}()
+
+	for range 100 {
+		if err := m.Import(bytes.NewReader(snap.Bytes())); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stop.Store(true)
+
+	if <-missed {
+		t.Fatal("a key kept by Import was missing while it ran")
+	}
+	if v := m.Get("gone"); v != 0 {
+		t.Fatalf("Get(gone) = %d after Import, want it removed", v)
+	}
+}
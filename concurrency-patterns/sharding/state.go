@@ -0,0 +1,67 @@
+package sharding
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// entry is one key-value pair of an exported ShardedMap.
+type entry[K comparable, V any] struct {
+	Key   K `json:"key"`
+	Value V `json:"value"`
+}
+
+// Export writes every key-value pair as JSON. All shards are read-locked for
+// the duration of the copy, so the snapshot is consistent across shards.
+func (m ShardedMap[K, V]) Export(w io.Writer) error {
+	for _, shard := range m {
+		shard.RLock()
+	}
+
+	var entries []entry[K, V]
+	for _, shard := range m {
+		for k, v := range shard.items {
+			entries = append(entries, entry[K, V]{k, v})
+		}
+	}
+
+	for _, shard := range m {
+		shard.RUnlock()
+	}
+
+	return json.NewEncoder(w).Encode(entries)
+}
+
+// Import replaces the contents of the map with a snapshot written by Export.
+// Keys are redistributed across the current shards, so the shard count may
+// differ from the one the snapshot was taken with.
+func (m ShardedMap[K, V]) Import(r io.Reader) error {
+	var entries []entry[K, V]
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return err
+	}
+
+	for _, shard := range m {
+		shard.Lock()
+	}
+	defer func() {
+		for _, shard := range m {
+			shard.Unlock()
+		}
+	}()
+
+	// Build fresh maps rather than clearing in place, since copy-on-write
+	// readers may still hold the old ones
+	items := make([]map[K]V, len(m))
+	for i := range m {
+		items[i] = make(map[K]V)
+	}
+	for _, e := range entries {
+		items[m.getShardIndex(e.Key)][e.Key] = e.Value
+	}
+	for i, shard := range m {
+		shard.publish(items[i])
+	}
+
+	return nil
+}
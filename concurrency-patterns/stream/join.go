@@ -0,0 +1,178 @@
+// Package stream correlates values flowing through channels.
+package stream
+
+import (
+	"time"
+
+	"github.com/1core-dev/cloud-native/stability-patterns/clock"
+)
+
+// Pair is the outcome of Join for one key. Left comes from the first input
+// and Right from the second. A nil side means no match arrived within the
+// window.
+type Pair[K comparable, T any] struct {
+	Key   K
+	Left  *T
+	Right *T
+}
+
+// Matched reports whether both sides are present.
+func (p Pair[K, T]) Matched() bool {
+	return p.Left != nil && p.Right != nil
+}
+
+// Option configures Join.
+type Option func(*options)
+
+type options struct {
+	clock clock.Clock
+}
+
+// WithClock sets the clock used to expire unmatched values. The default is
+// clock.Real.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// Join correlates values of a and b by key: a value from one input is paired
+// with the oldest value of the same key from the other input that arrived at
+// most window earlier. A value still unmatched window after it arrived is
+// emitted alone, with the other side nil.
+//
+// Values with the same key on the same side are matched in arrival order.
+// Once both inputs are closed, the values still waiting are emitted alone
+// and the returned channel is closed.
+func Join[K comparable, T any](a, b <-chan T, keyFn func(T) K, window time.Duration, opts ...Option) <-chan Pair[K, T] {
+	o := options{clock: clock.Real}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	out := make(chan Pair[K, T])
+
+	go func() {
+		defer close(out)
+
+		j := joiner[K, T]{
+			pending: [2]map[K][]*waiting[K, T]{{}, {}},
+		}
+
+		for a != nil || b != nil {
+			var fire <-chan time.Time
+			var timer clock.Timer
+			if len(j.order) > 0 {
+				timer = o.clock.NewTimer(j.order[0].deadline.Sub(o.clock.Now()))
+				fire = timer.C()
+			}
+
+			select {
+			case v, ok := <-a:
+				if !ok {
+					a = nil // Stop selecting on it
+					break
+				}
+				now := o.clock.Now()
+				j.expire(out, now) // Partners past their window must not match
+				j.arrive(out, 0, keyFn(v), v, now.Add(window))
+			case v, ok := <-b:
+				if !ok {
+					b = nil
+					break
+				}
+				now := o.clock.Now()
+				j.expire(out, now) // Partners past their window must not match
+				j.arrive(out, 1, keyFn(v), v, now.Add(window))
+			case now := <-fire:
+				j.expire(out, now)
+			}
+
+			if timer != nil {
+				timer.Stop()
+			}
+		}
+
+		// No match can arrive any more: flush what is left
+		j.expire(out, time.Time{})
+	}()
+
+	return out
+}
+
+// waiting is a value waiting for a match.
+type waiting[K comparable, T any] struct {
+	side     int // 0 for the first input, 1 for the second
+	key      K
+	value    T
+	deadline time.Time
+	matched  bool
+}
+
+// joiner holds the unmatched values of Join.
+type joiner[K comparable, T any] struct {
+	pending [2]map[K][]*waiting[K, T] // per side and key, oldest first
+	order   []*waiting[K, T]          // every waiting value, oldest first
+}
+
+// arrive pairs v with the oldest waiting value of the other side, or queues
+// it until deadline.
+func (j *joiner[K, T]) arrive(out chan<- Pair[K, T], side int, key K, v T, deadline time.Time) {
+	other := j.pending[1-side]
+
+	if q := other[key]; len(q) > 0 {
+		w := q[0]
+		w.matched = true // Skipped when its deadline passes
+		if len(q) == 1 {
+			delete(other, key)
+		} else {
+			other[key] = q[1:]
+		}
+
+		p := Pair[K, T]{Key: key}
+		if side == 0 {
+			p.Left, p.Right = &v, &w.value
+		} else {
+			p.Left, p.Right = &w.value, &v
+		}
+		out <- p
+		return
+	}
+
+	w := &waiting[K, T]{side: side, key: key, value: v, deadline: deadline}
+	j.pending[side][key] = append(j.pending[side][key], w)
+	j.order = append(j.order, w)
+}
+
+// expire emits the values whose deadline passed by now alone. A zero now
+// expires every value.
+func (j *joiner[K, T]) expire(out chan<- Pair[K, T], now time.Time) {
+	for len(j.order) > 0 {
+		w := j.order[0]
+		if !now.IsZero() && w.deadline.After(now) {
+			return
+		}
+		j.order[0] = nil
+		j.order = j.order[1:]
+
+		if w.matched {
+			continue
+		}
+
+		// Deadlines grow in arrival order, so w is the oldest of its key
+		q := j.pending[w.side]
+		if len(q[w.key]) == 1 {
+			delete(q, w.key)
+		} else {
+			q[w.key] = q[w.key][1:]
+		}
+
+		p := Pair[K, T]{Key: w.key}
+		if w.side == 0 {
+			p.Left = &w.value
+		} else {
+			p.Right = &w.value
+		}
+		out <- p
+	}
+}
@@ -0,0 +1,193 @@
+// Package wfq implements a weighted fair queueing dispatcher that shares a
+// worker pool's capacity between tenants.
+//
+// Each tenant has its own queue. The dispatcher hands items to workers so
+// that, while several tenants have work queued, each receives a share of the
+// throughput proportional to its weight. A tenant that floods the system only
+// fills its own queue and cannot starve the others.
+package wfq
+
+import (
+	"errors"
+	"sync"
+)
+
+// DefaultWeight is the weight of tenants without a configured one.
+const DefaultWeight = 1
+
+var (
+	// ErrQueueFull signals that the tenant's queue is at capacity.
+	ErrQueueFull = errors.New("tenant queue full")
+
+	// ErrClosed signals that the dispatcher no longer accepts items.
+	ErrClosed = errors.New("dispatcher closed")
+)
+
+// Dispatcher sends items from per-tenant queues to a shared output channel,
+// in weighted fair order.
+//
+// It uses virtual finish times: every item is stamped with the time its
+// tenant would finish it if it were served at a rate proportional to its
+// weight, and the item with the earliest stamp is dispatched next.
+type Dispatcher[T any] struct {
+	maxQueue int
+	out      chan T
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	tenants map[string]*tenant[T] // tenants with items queued
+	weights map[string]int
+	vtime   float64 // finish time of the last dispatched item
+	closed  bool
+}
+
+// tenant is one tenant's queue and the finish time of its last item.
+type tenant[T any] struct {
+	queue  []stamped[T]
+	finish float64
+}
+
+// stamped is a queued item with its virtual finish time.
+type stamped[T any] struct {
+	item   T
+	finish float64
+}
+
+// New returns a running Dispatcher with the given tenant weights. Each tenant
+// may have at most maxQueue items waiting; 0 means no limit.
+func New[T any](weights map[string]int, maxQueue int) *Dispatcher[T] {
+	d := &Dispatcher[T]{
+		maxQueue: maxQueue,
+		out:      make(chan T),
+		tenants:  make(map[string]*tenant[T]),
+		weights:  make(map[string]int, len(weights)),
+	}
+	d.cond = sync.NewCond(&d.mu)
+
+	for name, w := range weights {
+		d.weights[name] = w
+	}
+
+	go d.dispatch()
+
+	return d
+}
+
+// Out returns the channel workers receive items from. It is closed after
+// Close once every queued item has been dispatched.
+func (d *Dispatcher[T]) Out() <-chan T {
+	return d.out
+}
+
+// Enqueue adds item to the tenant's queue. It returns ErrQueueFull if the
+// queue is at capacity and ErrClosed after Close.
+func (d *Dispatcher[T]) Enqueue(name string, item T) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return ErrClosed
+	}
+
+	t := d.tenants[name]
+	if t == nil {
+		t = &tenant[T]{}
+		d.tenants[name] = t
+	}
+
+	if d.maxQueue > 0 && len(t.queue) >= d.maxQueue {
+		return ErrQueueFull
+	}
+
+	// A tenant that was idle starts from the current virtual time, so it
+	// can't claim credit for the time it had nothing queued
+	t.finish = max(t.finish, d.vtime) + 1/float64(d.weight(name))
+	t.queue = append(t.queue, stamped[T]{item, t.finish})
+
+	d.cond.Signal()
+	return nil
+}
+
+// SetWeight changes a tenant's weight. It applies to items enqueued
+// afterwards.
+func (d *Dispatcher[T]) SetWeight(name string, w int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.weights[name] = w
+}
+
+// Len returns the number of items waiting in the tenant's queue.
+func (d *Dispatcher[T]) Len(name string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if t := d.tenants[name]; t != nil {
+		return len(t.queue)
+	}
+	return 0
+}
+
+// Close stops accepting items. Items already queued are still dispatched.
+func (d *Dispatcher[T]) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.closed = true
+	d.cond.Broadcast()
+}
+
+// weight returns the weight of the tenant. Callers must hold d.mu.
+func (d *Dispatcher[T]) weight(name string) int {
+	if w, ok := d.weights[name]; ok && w > 0 {
+		return w
+	}
+	return DefaultWeight
+}
+
+// dispatch sends the queued item with the earliest finish time to out until
+// the dispatcher is closed and drained.
+func (d *Dispatcher[T]) dispatch() {
+	defer close(d.out)
+
+	for {
+		d.mu.Lock()
+
+		var (
+			next *tenant[T]
+			name string
+		)
+		for {
+			next = nil
+			for n, t := range d.tenants {
+				if next == nil || t.queue[0].finish < next.queue[0].finish {
+					next, name = t, n
+				}
+			}
+
+			if next != nil || d.closed {
+				break
+			}
+			d.cond.Wait()
+		}
+
+		if next == nil {
+			d.mu.Unlock()
+			return // Closed and drained
+		}
+
+		s := next.queue[0]
+		next.queue[0] = stamped[T]{} // Drop the reference for the GC
+		next.queue = next.queue[1:]
+		d.vtime = s.finish
+
+		// An emptied tenant would restart from vtime anyway, so forget it
+		if len(next.queue) == 0 {
+			delete(d.tenants, name)
+		}
+
+		d.mu.Unlock()
+
+		d.out <- s.item // Blocks until a worker is free
+	}
+}
@@ -8,21 +8,32 @@ import (
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/1core-dev/cloud-native/concurrency-patterns/panics"
+	"github.com/1core-dev/cloud-native/concurrency-patterns/result"
 )
 
 // worker processes jobs from the jobs channel and sends the results to the results channel.
 // It runs in its own goroutine and processes jobs concurrently.
-func worker(id int, jobs <-chan int, results chan<- int) {
+func worker(id int, jobs <-chan int, results chan<- result.Result[int]) {
 	for j := range jobs {
-		fmt.Println("Worker", id, "started job", j)
-		time.Sleep(time.Second) // Simulate processing by sleeping for 1 second
-		results <- j * 10       // Send result, job input multiplied by 10
+		var r int
+
+		// A panicking job is reported through the panics package and
+		// yields an error result, so the worker keeps serving jobs.
+		err := panics.Catch("workerpool", func() {
+			fmt.Println("Worker", id, "started job", j)
+			time.Sleep(time.Second) // Simulate processing by sleeping for 1 second
+			r = j * 10              // Job input multiplied by 10
+		})
+
+		results <- result.Of(r, err) // Send result
 	}
 }
 
 func main() {
 	jobs := make(chan int, 25)
-	results := make(chan int)
+	results := make(chan result.Result[int])
 	wg := sync.WaitGroup{}
 
 	for w := 1; w <= 3; w++ { // Spawn 3 workers processes
@@ -41,7 +52,11 @@ func main() {
 	}()
 
 	for r := range results {
-		fmt.Println("Got result:", r)
+		if r.Err != nil {
+			fmt.Println("Job failed:", r.Err)
+		} else {
+			fmt.Println("Got result:", r.Value)
+		}
 		wg.Done()
 	}
 
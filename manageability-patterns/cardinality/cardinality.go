@@ -0,0 +1,123 @@
+// Package cardinality bounds the number of distinct label values that
+// per-key metrics can produce, so per-tenant breakers and throttles don't
+// explode the number of time series in large multi-tenant deployments.
+//
+// Route templating collapses request paths into a fixed set of shapes, and a
+// TopK keeps the busiest keys under their own label while folding the long
+// tail into Other.
+package cardinality
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Other is the label value that untracked keys are folded into.
+const Other = "other"
+
+// Placeholder replaces path segments that look like identifiers in Template.
+const Placeholder = ":id"
+
+// Template collapses path segments that look like identifiers (decimal
+// numbers, UUIDs, and long hex strings) into Placeholder, so that
+// "/users/42/orders/7f3a9c0e1b2d4f56" becomes "/users/:id/orders/:id".
+func Template(path string) string {
+	segs := strings.Split(path, "/")
+	for i, s := range segs {
+		if isIdentifier(s) {
+			segs[i] = Placeholder
+		}
+	}
+	return strings.Join(segs, "/")
+}
+
+// Route returns the templated path of r, for use as a route label. Requests
+// matched by a pattern on http.ServeMux are labeled with the pattern instead.
+func Route(r *http.Request) string {
+	if r.Pattern != "" {
+		return r.Pattern
+	}
+	return Template(r.URL.Path)
+}
+
+// isIdentifier reports whether s looks like a generated identifier rather
+// than a fixed route segment.
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	digits, hex := true, true
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+		case c >= 'a' && c <= 'f', c >= 'A' && c <= 'F':
+			digits = false
+		case c == '-' && len(s) == 36: // UUID
+			digits = false
+		default:
+			return false
+		}
+	}
+
+	return digits || (hex && len(s) >= 16)
+}
+
+// TopK keeps its own label for at most k of the most frequent keys, and maps
+// every other key to Other. It is safe for concurrent use.
+//
+// Frequencies are estimated with the Misra-Gries algorithm: a heavy key
+// keeps its slot, and a new key takes one only once the slot's previous
+// owner has become rare. The set of labels therefore stays stable under a
+// steady workload while still adapting when the hot keys change.
+type TopK struct {
+	k      int
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// NewTopK returns a TopK tracking at most k keys.
+func NewTopK(k int) *TopK {
+	return &TopK{k: k, counts: make(map[string]int, k)}
+}
+
+// Label records an occurrence of key and returns the label to report it
+// under: key itself if it is tracked, Other if not.
+func (t *TopK) Label(key string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.counts[key]; ok {
+		t.counts[key]++
+		return key
+	}
+
+	if len(t.counts) < t.k {
+		t.counts[key] = 1
+		return key
+	}
+
+	// Full: wear every tracked key down, freeing the slots of rare ones
+	for k, n := range t.counts {
+		if n <= 1 {
+			delete(t.counts, k)
+		} else {
+			t.counts[k] = n - 1
+		}
+	}
+
+	return Other
+}
+
+// Keys returns the keys currently reported under their own label.
+func (t *TopK) Keys() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	keys := make([]string, 0, len(t.counts))
+	for k := range t.counts {
+		keys = append(keys, k)
+	}
+	return keys
+}
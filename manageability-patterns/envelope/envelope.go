@@ -0,0 +1,156 @@
+// Package envelope frames on-disk state in a small self-describing binary
+// envelope, so files written by the toolkit can be identified, versioned,
+// and checked for corruption before they are trusted.
+//
+// An envelope is an 18-byte header followed by the payload:
+//
+//	offset  size  field
+//	0       4     magic "CNEV"
+//	4       1     format version
+//	5       1     flags (bit 0: payload is gzip-compressed)
+//	6       8     payload length, big-endian
+//	14      4     CRC-32 (Castagnoli) of the stored payload, big-endian
+package envelope
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// Version is the envelope format version written by Encode.
+const Version = 1
+
+// headerSize is the length of the envelope header in bytes.
+const headerSize = 18
+
+// flagGzip marks a gzip-compressed payload.
+const flagGzip = 1 << 0
+
+var magic = [4]byte{'C', 'N', 'E', 'V'}
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+var (
+	// ErrBadMagic signals that the data is not an envelope.
+	ErrBadMagic = errors.New("envelope: bad magic")
+
+	// ErrChecksum signals that the payload does not match its checksum.
+	ErrChecksum = errors.New("envelope: checksum mismatch")
+
+	// ErrTooLarge signals that the payload exceeds the decoder's limit.
+	ErrTooLarge = errors.New("envelope: payload too large")
+)
+
+// VersionError signals an envelope written in an unsupported format version.
+type VersionError struct {
+	Version uint8
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("envelope: unsupported version %d", e.Version)
+}
+
+// Encode writes payload to w in an envelope, gzip-compressing it first if
+// compress is set.
+func Encode(w io.Writer, payload []byte, compress bool) error {
+	var flags uint8
+
+	if compress {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(payload); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+
+		payload = buf.Bytes()
+		flags |= flagGzip
+	}
+
+	var hdr [headerSize]byte
+	copy(hdr[0:4], magic[:])
+	hdr[4] = Version
+	hdr[5] = flags
+	binary.BigEndian.PutUint64(hdr[6:14], uint64(len(payload)))
+	binary.BigEndian.PutUint32(hdr[14:18], crc32.Checksum(payload, castagnoli))
+
+	if _, err := w.Write(hdr[:]); err != nil {
+		return err
+	}
+
+	_, err := w.Write(payload)
+	return err
+}
+
+// Decode reads an envelope from r, verifies it, and returns the payload,
+// decompressed if needed. Payloads longer than maxSize bytes are rejected
+// with ErrTooLarge before being read; a maxSize of 0 means no limit.
+func Decode(r io.Reader, maxSize int64) ([]byte, error) {
+	var hdr [headerSize]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("envelope: read header: %w", err)
+	}
+
+	if !bytes.Equal(hdr[0:4], magic[:]) {
+		return nil, ErrBadMagic
+	}
+	if v := hdr[4]; v != Version {
+		return nil, &VersionError{Version: v}
+	}
+
+	flags := hdr[5]
+	size := binary.BigEndian.Uint64(hdr[6:14])
+	sum := binary.BigEndian.Uint32(hdr[14:18])
+
+	if size > uint64(1<<63-1) || (maxSize > 0 && int64(size) > maxSize) {
+		return nil, ErrTooLarge
+	}
+
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r, int64(size)); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("envelope: read payload: %w", err)
+	}
+
+	payload := buf.Bytes()
+	if crc32.Checksum(payload, castagnoli) != sum {
+		return nil, ErrChecksum
+	}
+
+	if flags&flagGzip == 0 {
+		return payload, nil
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("envelope: decompress: %w", err)
+	}
+	defer zr.Close()
+
+	var out io.Reader = zr
+	if maxSize > 0 {
+		out = io.LimitReader(zr, maxSize+1) // Guard against decompression bombs
+	}
+
+	data, err := io.ReadAll(out)
+	if err != nil {
+		return nil, fmt.Errorf("envelope: decompress: %w", err)
+	}
+	if maxSize > 0 && int64(len(data)) > maxSize {
+		return nil, ErrTooLarge
+	}
+
+	return data, nil
+}
@@ -0,0 +1,40 @@
+package envelope
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// WriteFile encodes payload in an envelope and stores it at path, replacing
+// the file atomically and durably: the envelope is written to a temporary
+// file in the same directory and flushed to disk, then renamed into place,
+// and the directory is flushed, where the platform allows it, so the rename
+// itself survives a crash. A reader sees either the old file or the new one,
+// never a partial or empty file, even after a power loss.
+func WriteFile(path string, payload []byte, compress bool) error {
+	dir := filepath.Dir(path)
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if err := Encode(tmp, payload, compress); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	return syncDir(dir)
+}
@@ -0,0 +1,9 @@
+//go:build windows
+
+package envelope
+
+// syncDir does nothing: Windows cannot flush a directory handle, and NTFS
+// journals the rename itself.
+func syncDir(string) error {
+	return nil
+}
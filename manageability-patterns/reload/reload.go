@@ -0,0 +1,171 @@
+// Package reload restarts a service without dropping requests.
+//
+// Two strategies are supported. With SO_REUSEPORT, the new process binds
+// the same address while the old one still serves, and the kernel spreads
+// new connections over both until the old one stops accepting. With
+// listener passing, the old process hands its listening sockets to a child
+// it starts, so connections queue up in the same socket throughout.
+//
+// Either way the old process then stops accepting and waits for its
+// in-flight requests, as tracked by an inflight.Tracker, before exiting:
+//
+//	ln, _ := reload.Listen("tcp", ":8080")
+//	go srv.Serve(ln)
+//
+//	<-sighup
+//	reload.Spawn(ln)
+//	reload.Handover(ctx, srv.Shutdown, tracker)
+package reload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/1core-dev/cloud-native/concurrency-patterns/inflight"
+)
+
+// envListeners names the environment variable through which Spawn tells the
+// child which listeners it inherits: a comma-separated list of
+// "network:address" entries, the first at file descriptor 3.
+const envListeners = "RELOAD_LISTENERS"
+
+// ErrUnsupported is returned by ListenReusePort on platforms without
+// SO_REUSEPORT support.
+var ErrUnsupported = errors.New("reload: SO_REUSEPORT not supported on this platform")
+
+var (
+	inheritOnce sync.Once
+	inheritMu   sync.Mutex
+	inherited   map[string]*os.File // by "network:address", until claimed
+)
+
+// Listen returns the listener for addr inherited from the parent through
+// Spawn if there is one, and otherwise binds a new one with SO_REUSEPORT,
+// falling back to a plain listener where it is unsupported.
+func Listen(network, addr string) (net.Listener, error) {
+	if f := claim(network, addr); f != nil {
+		defer f.Close() // FileListener duplicates it
+		return net.FileListener(f)
+	}
+
+	ln, err := ListenReusePort(network, addr)
+	if errors.Is(err, ErrUnsupported) {
+		return net.Listen(network, addr)
+	}
+	return ln, err
+}
+
+// Inherited reports whether the process was started by Spawn with
+// listeners to inherit.
+func Inherited() bool {
+	return os.Getenv(envListeners) != ""
+}
+
+// claim returns the inherited file for network and addr, at most once.
+func claim(network, addr string) *os.File {
+	inheritOnce.Do(func() {
+		inherited = make(map[string]*os.File)
+
+		v := os.Getenv(envListeners)
+		if v == "" {
+			return
+		}
+		for i, name := range strings.Split(v, ",") {
+			inherited[name] = os.NewFile(uintptr(3+i), name)
+		}
+	})
+
+	inheritMu.Lock()
+	defer inheritMu.Unlock()
+
+	name := network + ":" + addr
+	f := inherited[name]
+	delete(inherited, name)
+	return f
+}
+
+// Spawn starts a new copy of the running executable, with the same
+// arguments and environment, handing it listeners. The child picks them up
+// by calling Listen with the same network and address. Listeners must be
+// *net.TCPListener or *net.UnixListener.
+func Spawn(listeners ...net.Listener) (*os.Process, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		files []*os.File
+		names []string
+	)
+	defer func() {
+		for _, f := range files {
+			f.Close() // The child holds its own copies
+		}
+	}()
+
+	for _, ln := range listeners {
+		fl, ok := ln.(interface{ File() (*os.File, error) })
+		if !ok {
+			return nil, fmt.Errorf("reload: cannot pass %T to a child", ln)
+		}
+
+		f, err := fl.File()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+		names = append(names, ln.Addr().Network()+":"+listenAddr(ln))
+	}
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = files
+	cmd.Env = append(environWithout(envListeners), envListeners+"="+strings.Join(names, ","))
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return cmd.Process, nil
+}
+
+// listenAddr is the address the child passes to Listen for ln: the one it
+// was bound to, which for ":8080" is the wildcard address.
+func listenAddr(ln net.Listener) string {
+	if a, ok := ln.Addr().(*net.TCPAddr); ok && a.IP.IsUnspecified() {
+		return fmt.Sprintf(":%d", a.Port)
+	}
+	return ln.Addr().String()
+}
+
+// environWithout returns the environment without the variable key.
+func environWithout(key string) []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, key+"=") {
+			env = append(env, kv)
+		}
+	}
+	return env
+}
+
+// Handover retires the old process once its replacement is serving: it
+// calls shutdown, typically http.Server.Shutdown, to stop accepting new
+// connections, then waits until tracker sees no work in flight. It returns
+// ctx.Err() if ctx is done first, in which case the remaining work is cut
+// off when the process exits. tracker may be nil.
+func Handover(ctx context.Context, shutdown func(context.Context) error, tracker *inflight.Tracker) error {
+	if err := shutdown(ctx); err != nil {
+		return err
+	}
+	if tracker == nil {
+		return nil
+	}
+	return tracker.Drain(ctx)
+}
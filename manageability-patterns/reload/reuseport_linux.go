@@ -0,0 +1,33 @@
+//go:build linux && !mips && !mipsle && !mips64 && !mips64le
+
+package reload
+
+import (
+	"context"
+	"net"
+	"syscall"
+)
+
+// soReusePort is SO_REUSEPORT, which the syscall package does not define
+// for every architecture.
+const soReusePort = 0xf
+
+// ListenReusePort binds addr with SO_REUSEPORT, so another process, such as
+// the next version of the service, can bind the same address and share its
+// incoming connections.
+func ListenReusePort(network, addr string) (net.Listener, error) {
+	lc := net.ListenConfig{
+		Control: func(_, _ string, c syscall.RawConn) error {
+			var opErr error
+			err := c.Control(func(fd uintptr) {
+				opErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, soReusePort, 1)
+			})
+			if err != nil {
+				return err
+			}
+			return opErr
+		},
+	}
+
+	return lc.Listen(context.Background(), network, addr)
+}
@@ -0,0 +1,11 @@
+//go:build !linux || mips || mipsle || mips64 || mips64le
+
+package reload
+
+import "net"
+
+// ListenReusePort returns ErrUnsupported: SO_REUSEPORT is only wired up on
+// Linux. Listen falls back to a plain listener.
+func ListenReusePort(network, addr string) (net.Listener, error) {
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,103 @@
+// Package state exports and restores the in-memory state of stateful
+// patterns, so a service can save it on shutdown and pick up where it left
+// off on startup.
+//
+// Types such as sharding.ShardedMap and circuitbreaker.BreakerGroup implement
+// Snapshotter. A Bundle combines several of them into one document, and
+// SaveFile and LoadFile persist it.
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/1core-dev/cloud-native/manageability-patterns/envelope"
+)
+
+// Snapshotter is implemented by types whose state can be exported and
+// restored.
+//
+// Export writes a consistent snapshot: no concurrent update is partially
+// included. Import replaces the current state with the snapshot.
+type Snapshotter interface {
+	Export(w io.Writer) error
+	Import(r io.Reader) error
+}
+
+// Bundle combines named Snapshotters into one. Its snapshot is a JSON object
+// holding each member's snapshot under its name.
+type Bundle map[string]Snapshotter
+
+// Export writes the snapshot of every member. Members must produce JSON.
+func (b Bundle) Export(w io.Writer) error {
+	doc := make(map[string]json.RawMessage, len(b))
+
+	for name, s := range b {
+		var buf bytes.Buffer
+		if err := s.Export(&buf); err != nil {
+			return fmt.Errorf("export %q: %w", name, err)
+		}
+		doc[name] = buf.Bytes()
+	}
+
+	return json.NewEncoder(w).Encode(doc)
+}
+
+// Import restores every member found in the snapshot. Members missing from
+// the snapshot are left untouched, so new members can be added to a Bundle
+// without invalidating old snapshots.
+func (b Bundle) Import(r io.Reader) error {
+	var doc map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return err
+	}
+
+	for name, s := range b {
+		raw, ok := doc[name]
+		if !ok {
+			continue
+		}
+		if err := s.Import(bytes.NewReader(raw)); err != nil {
+			return fmt.Errorf("import %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// SaveFile writes the snapshot of s to path, compressed in a checksummed
+// envelope. The file is replaced atomically and flushed to disk, so a crash
+// or power loss never leaves a partial file; see envelope.WriteFile.
+func SaveFile(path string, s Snapshotter) error {
+	var buf bytes.Buffer
+	if err := s.Export(&buf); err != nil {
+		return err
+	}
+
+	return envelope.WriteFile(path, buf.Bytes(), true)
+}
+
+// LoadFile restores s from the snapshot at path. A missing file is not an
+// error and leaves s untouched; a corrupted one is rejected before s is.
+func LoadFile(path string, s Snapshotter) error {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	data, err := envelope.Decode(f, 0)
+	if err != nil {
+		return fmt.Errorf("load %s: %w", path, err)
+	}
+
+	return s.Import(bytes.NewReader(data))
+}
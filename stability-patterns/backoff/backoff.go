@@ -0,0 +1,179 @@
+// Package backoff provides strategies for spacing out repeated attempts
+// against a service that is failing, shared by the retry and circuit breaker
+// patterns.
+//
+// Growing delays give a struggling service room to recover, and jitter spreads
+// out the attempts of many clients so they don't retry in lockstep.
+package backoff
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff computes how long to wait before an attempt.
+//
+// Implementations are stateless and safe for concurrent use, so one Backoff
+// can be shared by many wrappers.
+type Backoff interface {
+	// Delay returns the wait before the given attempt, counting from 0.
+	Delay(attempt int) time.Duration
+}
+
+// Default returns the strategy used when none is configured: exponential
+// growth from 2 seconds, capped at 5 minutes, with jitter.
+func Default() Backoff {
+	return ExponentialJitter{Base: 2 * time.Second, Max: 5 * time.Minute}
+}
+
+// Constant waits the same Interval before every attempt.
+type Constant struct {
+	Interval time.Duration
+}
+
+// Delay returns c.Interval.
+func (c Constant) Delay(int) time.Duration {
+	return c.Interval
+}
+
+// Exponential doubles the delay with every attempt, starting from Base and
+// never exceeding Max. A zero Max means no cap.
+type Exponential struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// Delay returns Base * 2^attempt, capped at Max.
+func (e Exponential) Delay(attempt int) time.Duration {
+	return exponential(e.Base, e.Max, attempt)
+}
+
+// ExponentialJitter grows like Exponential, but picks each delay at random
+// between half and all of the exponential value. Clients that failed together
+// thus spread out their next attempts while still backing off.
+type ExponentialJitter struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// Delay returns a random duration in [d/2, d], where d is the capped
+// exponential delay for attempt.
+func (e ExponentialJitter) Delay(attempt int) time.Duration {
+	d := exponential(e.Base, e.Max, attempt)
+	if d <= 1 {
+		return d
+	}
+
+	half := d / 2
+	return half + upTo(d-half)
+}
+
+// FullJitter grows like Exponential, but picks each delay at random between
+// zero and the exponential value. It spreads clients out the most, at the
+// cost of occasionally retrying almost immediately.
+type FullJitter struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// Delay returns a random duration in [0, d], where d is the capped
+// exponential delay for attempt.
+func (f FullJitter) Delay(attempt int) time.Duration {
+	d := exponential(f.Base, f.Max, attempt)
+	if d <= 0 {
+		return 0
+	}
+
+	return upTo(d)
+}
+
+// DecorrelatedJitter picks each delay at random between Base and a bound
+// that triples with every attempt, never exceeding Max. A zero Max means no
+// cap.
+//
+// The classic decorrelated jitter derives each delay from the previous one;
+// this variant derives the bound from the attempt instead, so it stays
+// stateless and safe to share.
+type DecorrelatedJitter struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// Delay returns a random duration in [Base, min(Base * 3^attempt, Max)].
+func (d DecorrelatedJitter) Delay(attempt int) time.Duration {
+	limit := capOf(d.Max)
+	if d.Base <= 0 {
+		return 0
+	}
+	if d.Base >= limit {
+		return limit
+	}
+
+	upper := d.Base
+	for range attempt {
+		if upper > limit/3 {
+			upper = limit
+			break
+		}
+		upper *= 3
+	}
+
+	return d.Base + upTo(upper-d.Base)
+}
+
+// Fibonacci grows the delay along the Fibonacci sequence (Base, Base, 2*Base,
+// 3*Base, 5*Base...), which is gentler than doubling. A zero Max means no cap.
+type Fibonacci struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// Delay returns Base * F(attempt+1), capped at Max.
+func (f Fibonacci) Delay(attempt int) time.Duration {
+	limit := capOf(f.Max)
+
+	a, b := f.Base, f.Base
+	for range attempt {
+		if b > limit-a { // a+b would exceed the cap (or overflow)
+			return limit
+		}
+		a, b = b, a+b
+	}
+
+	return min(a, limit)
+}
+
+// exponential returns base * 2^attempt without overflowing, capped at ceiling.
+func exponential(base, ceiling time.Duration, attempt int) time.Duration {
+	limit := capOf(ceiling)
+
+	if base <= 0 {
+		return 0
+	}
+	if attempt < 0 {
+		attempt = 0
+	}
+	if attempt >= 63 || base > limit>>attempt {
+		return limit
+	}
+
+	return base << attempt
+}
+
+// upTo returns a random duration in [0, d]. For the largest duration, whose
+// successor would overflow, it leaves out d itself.
+func upTo(d time.Duration) time.Duration {
+	if d == math.MaxInt64 {
+		return rand.N(d)
+	}
+	return rand.N(d + 1)
+}
+
+// capOf returns the upper bound for ceiling, where zero or less means none.
+func capOf(ceiling time.Duration) time.Duration {
+	if ceiling <= 0 {
+		return math.MaxInt64
+	}
+	return ceiling
+}
@@ -0,0 +1,63 @@
+package backoff
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDelayBounds(t *testing.T) {
+	tests := []struct {
+		name     string
+		b        Backoff
+		min, max time.Duration // bounds of the delay at large attempts
+	}{
+		{"constant", Constant{Interval: time.Second}, time.Second, time.Second},
+		{"exponential", Exponential{Base: time.Second}, math.MaxInt64, math.MaxInt64},
+		{"exponential capped", Exponential{Base: time.Second, Max: time.Minute}, time.Minute, time.Minute},
+		{"exponential jitter", ExponentialJitter{Base: time.Second}, math.MaxInt64 / 2, math.MaxInt64},
+		{"exponential jitter capped", ExponentialJitter{Base: time.Second, Max: time.Minute}, 30 * time.Second, time.Minute},
+		{"full jitter", FullJitter{Base: time.Second}, 0, math.MaxInt64},
+		{"full jitter capped", FullJitter{Base: time.Second, Max: time.Minute}, 0, time.Minute},
+		{"decorrelated jitter", DecorrelatedJitter{Base: time.Second}, time.Second, math.MaxInt64},
+		{"decorrelated jitter capped", DecorrelatedJitter{Base: time.Second, Max: time.Minute}, time.Second, time.Minute},
+		{"fibonacci", Fibonacci{Base: time.Second}, time.Second, math.MaxInt64},
+		{"fibonacci capped", Fibonacci{Base: time.Second, Max: time.Minute}, time.Minute, time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, attempt := range []int{40, 62, 63, 64, 100, 1000, math.MaxInt32} {
+				for range 100 { // Jitter: sample each attempt several times
+					d := tt.b.Delay(attempt)
+					if d < tt.min || d > tt.max {
+						t.Fatalf("Delay(%d) = %v, want in [%v, %v]", attempt, d, tt.min, tt.max)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestDelayGrowth(t *testing.T) {
+	tests := []struct {
+		name string
+		b    Backoff
+		want []time.Duration
+	}{
+		{"exponential", Exponential{Base: time.Second, Max: 10 * time.Second},
+			[]time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second}},
+		{"fibonacci", Fibonacci{Base: time.Second},
+			[]time.Duration{time.Second, time.Second, 2 * time.Second, 3 * time.Second, 5 * time.Second}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for attempt, want := range tt.want {
+				if got := tt.b.Delay(attempt); got != want {
+					t.Errorf("Delay(%d) = %v, want %v", attempt, got, want)
+				}
+			}
+		})
+	}
+}
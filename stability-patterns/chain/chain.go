@@ -0,0 +1,147 @@
+// Package chain assembles resilience layers into a named, inspectable chain.
+// Unlike nested closures, a Chain remembers what it is made of: it can list
+// its layers in order, and counts the calls, errors, and latency seen at each
+// one, so operators can tell which layer is rejecting or slowing traffic.
+//
+//	c := chain.Build(fetch,
+//		chain.Timeout(2*time.Second),
+//		chain.Retry(3, 100*time.Millisecond),
+//		chain.Breaker(cb),
+//		chain.Throttle(100, 10, time.Second),
+//	)
+//	fmt.Println(c) // timeout -> retry -> breaker -> throttle
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	circuitbreaker "github.com/1core-dev/cloud-native/stability-patterns/circuit-breaker"
+	"github.com/1core-dev/cloud-native/stability-patterns/resilience"
+)
+
+// Layer is a resilience layer with the name it is reported under.
+type Layer struct {
+	Name string
+	Wrap resilience.Layer
+}
+
+// Named names a resilience layer.
+func Named(name string, wrap resilience.Layer) Layer {
+	return Layer{Name: name, Wrap: wrap}
+}
+
+// Timeout is resilience.Timeout, named "timeout".
+func Timeout(d time.Duration) Layer {
+	return Named("timeout", resilience.Timeout(d))
+}
+
+// Retry is resilience.Retry, named "retry".
+func Retry(maxRetries int, delay time.Duration) Layer {
+	return Named("retry", resilience.Retry(maxRetries, delay))
+}
+
+// Breaker is resilience.BreakerOf, named "breaker".
+func Breaker(cb *circuitbreaker.CircuitBreaker) Layer {
+	return Named("breaker", resilience.BreakerOf(cb))
+}
+
+// Throttle is resilience.Throttle, named "throttle".
+func Throttle(max, refill uint, d time.Duration) Layer {
+	return Named("throttle", resilience.Throttle(max, refill, d))
+}
+
+// Stats holds what a layer has seen. A layer's counts include the time and
+// errors of every layer inside it.
+type Stats struct {
+	Name    string        `json:"name"`
+	Calls   uint64        `json:"calls"`
+	Errors  uint64        `json:"errors"`
+	Latency time.Duration `json:"latency"` // cumulative
+}
+
+// counters are the live Stats of one layer.
+type counters struct {
+	calls   atomic.Uint64
+	errors  atomic.Uint64
+	latency atomic.Int64
+}
+
+// Chain is a function wrapped with named layers. It is safe for concurrent
+// use.
+type Chain struct {
+	fn     resilience.Func
+	names  []string
+	counts []*counters
+}
+
+// Build wraps fn with layers. The first layer is the outermost: it sees a
+// call first and its result last.
+func Build(fn resilience.Func, layers ...Layer) *Chain {
+	c := &Chain{
+		names:  make([]string, len(layers)),
+		counts: make([]*counters, len(layers)),
+	}
+
+	for i := len(layers) - 1; i >= 0; i-- {
+		cnt := new(counters)
+		c.names[i], c.counts[i] = layers[i].Name, cnt
+		fn = instrument(layers[i].Wrap(fn), cnt)
+	}
+	c.fn = fn
+
+	return c
+}
+
+// instrument counts the calls through fn in cnt.
+func instrument(fn resilience.Func, cnt *counters) resilience.Func {
+	return func(ctx context.Context) (string, error) {
+		start := time.Now()
+		response, err := fn(ctx)
+
+		cnt.calls.Add(1)
+		cnt.latency.Add(int64(time.Since(start)))
+		if err != nil {
+			cnt.errors.Add(1)
+		}
+
+		return response, err
+	}
+}
+
+// Call runs ctx through the chain.
+func (c *Chain) Call(ctx context.Context) (string, error) {
+	return c.fn(ctx)
+}
+
+// Func returns the chain as a plain function.
+func (c *Chain) Func() resilience.Func {
+	return c.fn
+}
+
+// Layers returns the stats of every layer, outermost first.
+func (c *Chain) Layers() []Stats {
+	stats := make([]Stats, len(c.names))
+	for i, cnt := range c.counts {
+		stats[i] = Stats{
+			Name:    c.names[i],
+			Calls:   cnt.calls.Load(),
+			Errors:  cnt.errors.Load(),
+			Latency: time.Duration(cnt.latency.Load()),
+		}
+	}
+	return stats
+}
+
+// String lists the layer names, outermost first.
+func (c *Chain) String() string {
+	return strings.Join(c.names, " -> ")
+}
+
+// MarshalJSON encodes the layers and their stats, outermost first.
+func (c *Chain) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.Layers())
+}
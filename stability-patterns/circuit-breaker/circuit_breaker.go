@@ -1,67 +1,645 @@
 // Package circuitbreaker protects services from overload.
 //
-// It blocks calls after N failures, applies exponential backoff while open,
-// and resets on success.
+// It blocks calls after N failures, applies a backoff (jittered exponential
+// by default) while open, and resets on success.
 package circuitbreaker
 
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
 	"sync"
 	"time"
+
+	"github.com/1core-dev/cloud-native/stability-patterns/backoff"
+	"github.com/1core-dev/cloud-native/stability-patterns/clock"
 )
 
 // ErrServiceUnavailable signals that the circuit is currently open.
 var ErrServiceUnavailable = errors.New("service unavailable")
 
+// PanicError is returned, when panic recovery is enabled, for a call whose
+// circuit panicked. The panic counts as a failure.
+type PanicError struct {
+	Value any    // value passed to panic
+	Stack []byte // stack trace of the panicking goroutine
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("circuit panicked: %v", e.Value)
+}
+
 // Circuit is a function that can be cancelled with context.
 type Circuit func(context.Context) (string, error)
 
+// State is the position of a circuit breaker.
+type State int
+
+const (
+	// Closed lets every call through.
+	Closed State = iota
+
+	// Open rejects calls with ErrServiceUnavailable until the backoff expires.
+	Open
+
+	// HalfOpen lets trial calls through after the backoff expired, a limited
+	// number at a time (one by default); the rest are rejected. Enough
+	// consecutive successes (one by default) close the circuit; a failure
+	// opens it again with a longer backoff.
+	HalfOpen
+)
+
+// String returns the lower-case name of the state.
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// Counts holds the call statistics of a breaker.
+type Counts struct {
+	Requests             uint64 `json:"requests"`   // calls that reached the circuit
+	Rejections           uint64 `json:"rejections"` // calls rejected while open
+	TotalSuccesses       uint64 `json:"total_successes"`
+	TotalFailures        uint64 `json:"total_failures"`
+	ConsecutiveSuccesses int    `json:"consecutive_successes"`
+	ConsecutiveFailures  int    `json:"consecutive_failures"`
+}
+
 // Breaker wraps a function with circuit breaker logic.
 // It tracks failures. After 'threshold' failures, it opens the circuit.
-// While open, it blocks calls for some time using exponential backoff.
+// While open, it blocks calls for some time using the configured backoff.
 // If a call succeeds, it resets the failure counter.
-func Breaker(circuit Circuit, threshold int) Circuit {
-	var (
-		failures int       // how many times the function failed
-		last     time.Time // when the last attempt happened
-		mu       sync.RWMutex
-	)
-
-	// Return a new circuit breaker function
+func Breaker(circuit Circuit, threshold int, opts ...Option) Circuit {
+	return New(threshold, opts...).Wrap(circuit)
+}
+
+// CircuitBreaker holds the state of a single circuit breaker. Besides running
+// calls, it lets operators inspect it and force it open or closed.
+type CircuitBreaker struct {
+	threshold int
+	opts      options
+
+	counts    Counts
+	openUntil time.Time // when the current backoff expires
+	tripped   bool      // forced open by Trip until Reset
+	probes    int       // trial calls in flight while half-open
+	probing   bool      // health check loop running while open
+	failures  ring      // most recent failures, for debugging
+	outcomes  outcomes  // most recent results while closed, for the failure rate
+	reported  State     // last state announced to listeners
+	remote    bool      // opened by a peer replica, not by local failures
+	dirty     bool      // state changed since it was last persisted
+	saving    bool      // a caller is writing it to the store
+	mu        sync.RWMutex
+
+	warmStart  time.Time // when the circuit last recovered, while warming up
+	warmCredit float64   // share of a call earned toward the next admission
+
+	name     string    // name in the registry, if registered
+	registry *Registry // registry to broadcast transitions to
+}
+
+// New returns a CircuitBreaker that opens after threshold consecutive
+// failures, resuming from persisted state if a store is configured.
+func New(threshold int, opts ...Option) *CircuitBreaker {
+	o := buildOptions(opts)
+	cb := newCircuitBreaker(threshold, o)
+
+	if o.name != "" {
+		cb.name, cb.registry = o.name, o.registry
+		o.registry.Register(o.name, cb)
+	}
+
+	return cb
+}
+
+// newCircuitBreaker returns a CircuitBreaker for resolved options.
+func newCircuitBreaker(threshold int, o options) *CircuitBreaker {
+	if o.backoff == nil {
+		o.backoff = backoff.Default()
+	}
+	if o.successThreshold < 1 {
+		o.successThreshold = 1
+	}
+	if o.maxProbes < 1 {
+		o.maxProbes = 1
+	}
+	if o.failureHistory < 0 {
+		o.failureHistory = 0
+	}
+	if o.healthInterval <= 0 {
+		o.healthInterval = time.Second
+	}
+	if o.clock == nil {
+		o.clock = clock.Real
+	}
+
+	cb := &CircuitBreaker{threshold: max(threshold, 1), opts: o}
+	cb.failures.buf = make([]Failure, o.failureHistory)
+	cb.outcomes.reset(cb.rateWindow())
+
+	if o.store != nil {
+		if snap, err := o.store.Load(); err == nil {
+			cb.restore(snap)
+		}
+	}
+	cb.reported = cb.state(cb.opts.clock.Now())
+
+	return cb
+}
+
+// Wrap returns a Circuit that runs circuit through the breaker.
+func (cb *CircuitBreaker) Wrap(circuit Circuit) Circuit {
 	return func(ctx context.Context) (string, error) {
-		mu.RLock()
+		return cb.Execute(ctx, circuit)
+	}
+}
 
-		d := failures - threshold
+// Execute runs circuit unless the breaker is open, in which case it returns
+// ErrServiceUnavailable without calling it.
+func (cb *CircuitBreaker) Execute(ctx context.Context, circuit Circuit) (string, error) {
+	cb.mu.Lock()
 
-		// Too many failures: wait before retrying
-		if d >= 0 {
-			shouldRetryAt := last.Add((2 << d) * time.Second)
+	state := cb.state(cb.opts.clock.Now())
+	from, to, changed := cb.observe()
 
-			if !time.Now().After(shouldRetryAt) {
-				mu.RUnlock()
-				return "", ErrServiceUnavailable
-			}
+	// Too many failures: wait before retrying, and let only a few trial
+	// calls through at once while half-open, and only a growing share of
+	// them while warming up after recovery
+	if state == Open || (state == HalfOpen && cb.probes >= cb.opts.maxProbes) ||
+		(state == Closed && !cb.admitWarm(cb.opts.clock.Now())) {
+		cb.counts.Rejections++
+		cb.mu.Unlock()
+		cb.notify(from, to, changed, true)
+		return "", ErrServiceUnavailable
+	}
+
+	if state == HalfOpen {
+		cb.probes++
+	}
+
+	cb.mu.Unlock()
+	cb.notify(from, to, changed, true)
+
+	// Execute the actual circuit function
+	start := cb.opts.clock.Now()
+	response, panicked, err := cb.run(ctx, circuit)
+	latency := cb.opts.clock.Since(start)
+
+	failure := err
+	if err != nil && !panicked && cb.opts.failureIf != nil && !cb.opts.failureIf(err) {
+		failure = nil // Not the downstream's fault
+	}
+
+	cb.mu.Lock()
+	cb.record(state, failure, latency, cb.callerCancelled(ctx, err))
+	from, to, changed = cb.observe()
+	cb.mu.Unlock()
+	cb.persist()
+	cb.notify(from, to, changed, true)
+
+	// Without recovery, propagate the panic once the state is consistent
+	if panicked && !cb.opts.recoverPanics {
+		panic(err.(*PanicError).Value)
+	}
+
+	return response, err
+}
+
+// run calls circuit, converting a panic into a *PanicError.
+func (cb *CircuitBreaker) run(ctx context.Context, circuit Circuit) (response string, panicked bool, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			response, panicked, err = "", true, &PanicError{Value: v, Stack: debug.Stack()}
 		}
+	}()
 
-		mu.RUnlock()
+	response, err = circuit(ctx)
+	return response, false, err
+}
 
-		// Execute the actual circuit function
-		response, err := circuit(ctx)
+// callerCancelled reports whether err only reflects the caller giving up,
+// because the caller's own ctx was cancelled or timed out.
+func (cb *CircuitBreaker) callerCancelled(ctx context.Context, err error) bool {
+	if cb.opts.countCancellations || ctx.Err() == nil {
+		return false
+	}
+
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
 
-		mu.Lock()
-		defer mu.Unlock()
+// record updates the counts with the outcome of a call admitted in state.
+// A call the caller cancelled says nothing about the downstream's health and
+// counts as neither success nor failure. Callers must hold cb.mu.
+func (cb *CircuitBreaker) record(state State, err error, latency time.Duration, cancelled bool) {
+	if state == HalfOpen {
+		cb.probes--
+	}
+
+	cb.counts.Requests++
 
-		last = time.Now()
+	if cancelled {
+		return
+	}
 
-		if err != nil {
-			failures++
-			return response, err
+	if state == Closed {
+		cb.outcomes.add(err != nil)
+	}
+
+	if err != nil {
+		cb.counts.TotalFailures++
+		cb.failures.add(Failure{Time: cb.opts.clock.Now(), Err: err, Latency: latency})
+		cb.counts.ConsecutiveFailures++
+		cb.counts.ConsecutiveSuccesses = 0
+
+		// Failing too often, if not in a row: open as if at the threshold
+		if rate, ok := cb.outcomes.rate(); ok && state == Closed && rate >= cb.opts.failureRate {
+			cb.counts.ConsecutiveFailures = max(cb.counts.ConsecutiveFailures, cb.threshold)
 		}
 
-		// Success: reset the failure count
-		failures = 0
+		// Too many failures: back off longer with each further failure
+		if d := cb.counts.ConsecutiveFailures - cb.threshold; d >= 0 {
+			cb.open(cb.opts.clock.Now(), d)
+		}
 
-		return response, nil
+		cb.save()
+		return
 	}
+
+	cb.counts.TotalSuccesses++
+	cb.counts.ConsecutiveSuccesses++
+
+	// Half-open: keep probing until enough successes in a row
+	if cb.counts.ConsecutiveFailures >= cb.threshold &&
+		cb.counts.ConsecutiveSuccesses < cb.opts.successThreshold {
+		return
+	}
+
+	// Recovered: ramp traffic back up rather than letting it all through
+	if cb.counts.ConsecutiveFailures >= cb.threshold && cb.opts.warmUp > 0 {
+		cb.warmStart = cb.opts.clock.Now()
+		cb.warmCredit = 0
+	}
+
+	// Success: reset the failure count
+	if cb.counts.ConsecutiveFailures > 0 {
+		cb.counts.ConsecutiveFailures = 0
+		cb.save()
+	}
+}
+
+// open starts the backoff that follows the d-th failure past the threshold,
+// counting from 0, at now. Callers must hold cb.mu.
+func (cb *CircuitBreaker) open(now time.Time, d int) {
+	cb.openUntil = now.Add(cb.opts.backoff.Delay(d))
+	cb.remote = false
+	cb.warmStart = time.Time{}
+	cb.outcomes.reset(cb.rateWindow())
+	cb.startProbing()
+	cb.save()
+}
+
+// rateWindow returns the number of calls the failure rate is judged over, or
+// zero if it is disabled. Callers must hold cb.mu.
+func (cb *CircuitBreaker) rateWindow() int {
+	if cb.opts.failureRate <= 0 {
+		return 0
+	}
+	return max(cb.opts.rateWindow, 0)
+}
+
+// admitWarm reports whether a call arriving at now while closed may go
+// through. During the warm-up after recovery, the share of calls let through
+// grows linearly from none to all. Callers must hold cb.mu.
+func (cb *CircuitBreaker) admitWarm(now time.Time) bool {
+	if cb.warmStart.IsZero() {
+		return true
+	}
+
+	elapsed := now.Sub(cb.warmStart)
+	if elapsed >= cb.opts.warmUp {
+		cb.warmStart = time.Time{} // Fully warmed up
+		return true
+	}
+
+	// Each call earns the current share; a whole one lets a call through
+	cb.warmCredit += float64(elapsed) / float64(cb.opts.warmUp)
+	if cb.warmCredit < 1 {
+		return false
+	}
+
+	cb.warmCredit--
+	return true
+}
+
+// Trip forces the breaker open. It rejects every call until Reset is called,
+// regardless of backoff.
+func (cb *CircuitBreaker) Trip() {
+	cb.mu.Lock()
+	cb.tripped = true
+	cb.save()
+	from, to, changed := cb.observe()
+	cb.mu.Unlock()
+	cb.persist()
+
+	cb.notify(from, to, changed, true)
+}
+
+// Reset closes the breaker and clears its consecutive failure count, undoing
+// a Trip or an open circuit.
+func (cb *CircuitBreaker) Reset() {
+	cb.mu.Lock()
+	cb.tripped = false
+	cb.remote = false
+	cb.warmStart = time.Time{}
+	cb.counts.ConsecutiveFailures = 0
+	cb.counts.ConsecutiveSuccesses = 0
+	cb.save()
+	from, to, changed := cb.observe()
+	cb.mu.Unlock()
+	cb.persist()
+
+	cb.notify(from, to, changed, true)
+}
+
+// LastFailures returns the most recent failures, oldest first, so on-call
+// engineers can see why the circuit opened.
+func (cb *CircuitBreaker) LastFailures() []Failure {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+
+	return cb.failures.list()
+}
+
+// Snapshot returns the breaker's persistable state: its consecutive failure
+// count, when its backoff expires, and whether it was tripped.
+func (cb *CircuitBreaker) Snapshot() Snapshot {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+
+	return cb.snapshot()
+}
+
+// Restore replaces the breaker's persistable state with snap, for example
+// one taken by Snapshot before a restart. A breaker restored while its
+// backoff has not expired resumes open.
+func (cb *CircuitBreaker) Restore(snap Snapshot) {
+	cb.mu.Lock()
+	cb.restore(snap)
+	cb.save()
+	from, to, changed := cb.observe()
+	cb.mu.Unlock()
+	cb.persist()
+
+	cb.notify(from, to, changed, false)
+}
+
+// State returns the current state of the breaker.
+func (cb *CircuitBreaker) State() State {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+
+	return cb.state(cb.opts.clock.Now())
+}
+
+// Counts returns a copy of the breaker's call statistics.
+func (cb *CircuitBreaker) Counts() Counts {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+
+	return cb.counts
+}
+
+// state returns the state at time now. Callers must hold cb.mu.
+func (cb *CircuitBreaker) state(now time.Time) State {
+	if cb.tripped {
+		return Open
+	}
+
+	if cb.counts.ConsecutiveFailures < cb.threshold {
+		return Closed
+	}
+
+	if !now.After(cb.openUntil) {
+		return Open
+	}
+
+	return HalfOpen
+}
+
+// save marks the current state to be persisted. Callers must hold cb.mu and
+// call persist after releasing it.
+func (cb *CircuitBreaker) save() {
+	cb.dirty = cb.opts.store != nil
+}
+
+// persist writes the state to the store if it changed, outside cb.mu so that
+// calls don't wait for disk or network I/O. Writes are coalesced: while one
+// caller writes, others only mark the state changed, and the writer saves the
+// latest snapshot once it is done. Callers must not hold cb.mu.
+func (cb *CircuitBreaker) persist() {
+	if cb.opts.store == nil {
+		return
+	}
+
+	cb.mu.Lock()
+	if cb.saving {
+		cb.mu.Unlock()
+		return
+	}
+	cb.saving = true
+
+	for cb.dirty {
+		cb.dirty = false
+		snap := cb.snapshot()
+
+		cb.mu.Unlock()
+		_ = cb.opts.store.Save(snap)
+		cb.mu.Lock()
+	}
+
+	cb.saving = false
+	cb.mu.Unlock()
+}
+
+// startProbing runs the health check in the background while the circuit is
+// open, moving it to half-open as soon as a check succeeds. Callers must
+// hold cb.mu.
+func (cb *CircuitBreaker) startProbing() {
+	hc := cb.opts.healthCheck
+	if hc == nil || cb.probing {
+		return
+	}
+	cb.probing = true
+
+	go func() {
+		ticker := cb.opts.clock.NewTicker(cb.opts.healthInterval)
+		defer ticker.Stop()
+
+		for range ticker.C() {
+			cb.mu.Lock()
+			if cb.tripped || cb.state(cb.opts.clock.Now()) != Open {
+				cb.probing = false // Recovered, or under manual control
+				cb.mu.Unlock()
+				return
+			}
+			cb.mu.Unlock()
+
+			ctx, cancel := context.WithTimeout(context.Background(), cb.opts.healthInterval)
+			err := hc(ctx)
+			cancel()
+
+			if err != nil {
+				continue
+			}
+
+			// Healthy again: end the backoff early
+			cb.mu.Lock()
+			if now := cb.opts.clock.Now(); !cb.tripped && cb.state(now) == Open {
+				cb.endBackoff(now)
+				cb.save()
+			}
+			cb.probing = false
+			from, to, changed := cb.observe()
+			cb.mu.Unlock()
+			cb.persist()
+
+			cb.notify(from, to, changed, true)
+			return
+		}
+	}()
+}
+
+// endBackoff ends the current backoff at now, so the circuit is half-open
+// from now on. Callers must hold cb.mu.
+func (cb *CircuitBreaker) endBackoff(now time.Time) {
+	cb.openUntil = now.Add(-time.Nanosecond) // Open lasts up to openUntil included
+}
+
+// observe compares the current state with the last announced one and
+// records it as announced. Callers must hold cb.mu and pass the result to
+// notify after releasing it.
+func (cb *CircuitBreaker) observe() (from, to State, changed bool) {
+	from, to = cb.reported, cb.state(cb.opts.clock.Now())
+	cb.reported = to
+	return from, to, from != to
+}
+
+// notify announces a state change to the OnStateChange callback and, if
+// broadcast is set, to peers replicating through the registry. Callers must
+// not hold cb.mu.
+func (cb *CircuitBreaker) notify(from, to State, changed, broadcast bool) {
+	if !changed {
+		return
+	}
+
+	level := slog.LevelInfo
+	if to == Open {
+		level = slog.LevelWarn
+	}
+	cb.opts.log().Log(context.Background(), level, "circuit breaker state changed",
+		"name", cb.name, "from", from.String(), "to", to.String())
+
+	if cb.opts.onStateChange != nil {
+		cb.opts.onStateChange(cb.name, from, to)
+	}
+
+	if broadcast && cb.registry != nil && cb.name != "" && to != HalfOpen {
+		cb.mu.RLock()
+		t := Transition{Name: cb.name, Open: to == Open, OpenUntil: cb.openUntil}
+		cb.mu.RUnlock()
+
+		cb.registry.broadcast(t)
+	}
+}
+
+// snapshot returns the persistable state. Callers must hold cb.mu.
+func (cb *CircuitBreaker) snapshot() Snapshot {
+	return Snapshot{
+		Failures:  cb.counts.ConsecutiveFailures,
+		OpenUntil: cb.openUntil,
+		Tripped:   cb.tripped,
+	}
+}
+
+// restore applies a persisted state. Callers must hold cb.mu.
+func (cb *CircuitBreaker) restore(snap Snapshot) {
+	cb.counts.ConsecutiveFailures = snap.Failures
+	cb.openUntil = snap.OpenUntil
+	cb.tripped = snap.Tripped
+}
+
+// SetThreshold changes how many consecutive failures open the circuit; it is
+// at least 1. It takes effect immediately: lowering it to or below the
+// current failure count of a closed circuit opens it at once, with the
+// backoff those failures call for, and raising it above the count of an
+// open one closes it.
+func (cb *CircuitBreaker) SetThreshold(n int) {
+	cb.mu.Lock()
+
+	now := cb.opts.clock.Now()
+	closed := cb.state(now) == Closed
+	cb.threshold = max(n, 1)
+	if d := cb.counts.ConsecutiveFailures - cb.threshold; closed && d >= 0 {
+		cb.open(now, d)
+	}
+
+	from, to, changed := cb.observe()
+	cb.mu.Unlock()
+	cb.persist()
+
+	cb.notify(from, to, changed, true)
+}
+
+// SetFailureRate changes the failure rate and window that open the circuit,
+// as set by WithFailureRate. The outcomes recorded so far are forgotten, so
+// the new rate is judged over a full window of calls from now on.
+func (cb *CircuitBreaker) SetFailureRate(rate float64, window int) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.opts.failureRate, cb.opts.rateWindow = rate, window
+	cb.outcomes.reset(cb.rateWindow())
+}
+
+// SetBackoff changes how long the circuit stays open after each failure past
+// the threshold; nil restores backoff.Default(). The current backoff, if
+// any, keeps its expiry.
+func (cb *CircuitBreaker) SetBackoff(b backoff.Backoff) {
+	if b == nil {
+		b = backoff.Default()
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.opts.backoff = b
+}
+
+// SetSuccessThreshold changes how many consecutive successful trial calls
+// close a half-open circuit.
+func (cb *CircuitBreaker) SetSuccessThreshold(n int) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.opts.successThreshold = max(n, 1)
+}
+
+// SetMaxProbes changes how many trial calls a half-open circuit lets through
+// concurrently.
+func (cb *CircuitBreaker) SetMaxProbes(n int) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.opts.maxProbes = max(n, 1)
 }
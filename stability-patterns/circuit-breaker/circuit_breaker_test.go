@@ -0,0 +1,202 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/1core-dev/cloud-native/stability-patterns/backoff"
+	"github.com/1core-dev/cloud-native/stability-patterns/clock"
+)
+
+// The benchmarks below cover the breaker's call path in each state. Each
+// reports allocations, which should stay at zero per call.
+
+func BenchmarkBreakerClosed(b *testing.B) {
+	cb := New(5)
+	circuit := func(context.Context) (string, error) { return "", nil }
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		cb.Execute(ctx, circuit)
+	}
+}
+
+func BenchmarkBreakerFailing(b *testing.B) {
+	cb := New(1 << 30) // Never opens
+	fail := errors.New("fail")
+	circuit := func(context.Context) (string, error) { return "", fail }
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		cb.Execute(ctx, circuit)
+	}
+}
+
+func BenchmarkBreakerOpen(b *testing.B) {
+	cb := New(1)
+	cb.Trip()
+	circuit := func(context.Context) (string, error) { return "", nil }
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		cb.Execute(ctx, circuit)
+	}
+}
+
+func BenchmarkBreakerClosedParallel(b *testing.B) {
+	cb := New(5)
+	circuit := func(context.Context) (string, error) {
+		return "", nil
+	}
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		ctx := context.Background()
+		for pb.Next() {
+			cb.Execute(ctx, circuit)
+		}
+	})
+}
+
+// failN fails the first n calls through cb.
+func failN(t *testing.T, cb *CircuitBreaker, n int) {
+	t.Helper()
+
+	fail := func(context.Context) (string, error) { return "", errors.New("fail") }
+	for range n {
+		cb.Execute(context.Background(), fail)
+	}
+}
+
+func TestHealthCheckEndsBackoff(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	checked := make(chan struct{}, 1)
+	healthy := func(context.Context) error {
+		checked <- struct{}{}
+		return nil
+	}
+	cb := New(1, WithClock(fc), WithBackoff(backoff.Constant{Interval: time.Hour}),
+		WithHealthCheck(healthy, time.Second))
+
+	failN(t, cb, 1)
+	if s := cb.State(); s != Open {
+		t.Fatalf("state after failing = %v, want open", s)
+	}
+
+	fc.BlockUntil(1) // The probe's ticker
+	fc.Advance(time.Second)
+	<-checked
+
+	// The clock stands still: the check alone must end the backoff
+	deadline := time.Now().Add(5 * time.Second)
+	for cb.State() != HalfOpen {
+		if time.Now().After(deadline) {
+			t.Fatalf("state after a healthy check = %v, want half-open", cb.State())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRemoteCloseEndsBackoff(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	cb := New(1, WithClock(fc))
+
+	cb.applyRemote(Transition{Open: true, OpenUntil: fc.Now().Add(time.Hour)})
+	if s := cb.State(); s != Open {
+		t.Fatalf("state after a remote open = %v, want open", s)
+	}
+
+	cb.applyRemote(Transition{Open: false})
+	if s := cb.State(); s != HalfOpen {
+		t.Fatalf("state after a remote close = %v, want half-open", s)
+	}
+}
+
+func TestSetThresholdOpensAtOnce(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	cb := New(5, WithClock(fc), WithBackoff(backoff.Constant{Interval: time.Minute}))
+
+	failN(t, cb, 3)
+	cb.SetThreshold(2)
+	if s := cb.State(); s != Open {
+		t.Fatalf("state after lowering the threshold below the failures = %v, want open", s)
+	}
+
+	fc.Advance(time.Minute + time.Nanosecond)
+	if s := cb.State(); s != HalfOpen {
+		t.Fatalf("state after the backoff = %v, want half-open", s)
+	}
+
+	cb.SetThreshold(10)
+	if s := cb.State(); s != Closed {
+		t.Fatalf("state after raising the threshold = %v, want closed", s)
+	}
+
+	cb.SetThreshold(0)
+	cb.Reset()
+	if s := cb.State(); s != Closed {
+		t.Fatalf("state with a threshold of 0 = %v, want closed until a failure", s)
+	}
+}
+
+func TestGroupSetThresholdNotifiesOutsideTheLock(t *testing.T) {
+	var g *BreakerGroup
+	opened := make(chan string, 1)
+	g = NewBreakerGroup(5, 0, OnStateChange(func(name string, _, to State) {
+		g.Len() // Calls back into the group
+		if to == Open {
+			opened <- name
+		}
+	}))
+
+	failN(t, g.Get("db"), 3)
+
+	done := make(chan struct{})
+	go func() {
+		g.SetThreshold(2)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("SetThreshold deadlocked on a hook calling into the group")
+	}
+	if len(opened) != 1 {
+		t.Fatal("lowering the threshold did not open the breaker")
+	}
+}
+
+func TestFailureRate(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	cb := New(3, WithClock(fc), WithFailureRate(0.5, 4))
+
+	ok := func(context.Context) (string, error) { return "", nil }
+	alternate := func() {
+		for range 2 {
+			failN(t, cb, 1)
+			cb.Execute(context.Background(), ok)
+		}
+	}
+
+	alternate() // Every other call fails, never three in a row
+	if s := cb.State(); s != Closed {
+		t.Fatalf("state before a full window = %v, want closed", s)
+	}
+	failN(t, cb, 1) // Window: ok, fail, ok, fail
+	if s := cb.State(); s != Open {
+		t.Fatalf("state at a failure rate of 1/2 = %v, want open", s)
+	}
+
+	cb.Reset()
+	cb.SetFailureRate(0.9, 4)
+	alternate()
+	failN(t, cb, 1)
+	if s := cb.State(); s != Closed {
+		t.Fatalf("state at 1/2 with a rate of 9/10 = %v, want closed", s)
+	}
+}
@@ -0,0 +1,90 @@
+package circuitbreaker
+
+import "time"
+
+// DefaultFailureHistory is how many recent failures a breaker keeps unless
+// configured with WithFailureHistory.
+const DefaultFailureHistory = 10
+
+// Failure records one failed call.
+type Failure struct {
+	Time    time.Time     // when the call returned
+	Err     error         // what it returned
+	Latency time.Duration // how long it took
+}
+
+// ring is a fixed-size buffer keeping the most recent failures.
+type ring struct {
+	buf  []Failure
+	next int // index the next failure is written to
+	full bool
+}
+
+// add records f, overwriting the oldest failure once the buffer is full.
+func (r *ring) add(f Failure) {
+	if len(r.buf) == 0 {
+		return
+	}
+
+	r.buf[r.next] = f
+	r.next = (r.next + 1) % len(r.buf)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// list returns a copy of the failures, oldest first.
+func (r *ring) list() []Failure {
+	if !r.full {
+		return append([]Failure(nil), r.buf[:r.next]...)
+	}
+
+	out := make([]Failure, 0, len(r.buf))
+	out = append(out, r.buf[r.next:]...)
+	return append(out, r.buf[:r.next]...)
+}
+
+// outcomes is a fixed-size buffer of whether the most recent calls failed,
+// from which the failure rate is judged.
+type outcomes struct {
+	buf      []bool
+	next     int // index the next outcome is written to
+	n        int // outcomes recorded, up to len(buf)
+	failures int // failures among them
+}
+
+// add records whether a call failed, overwriting the oldest outcome once the
+// buffer is full.
+func (o *outcomes) add(failed bool) {
+	if len(o.buf) == 0 {
+		return
+	}
+
+	if o.n == len(o.buf) {
+		if o.buf[o.next] {
+			o.failures--
+		}
+	} else {
+		o.n++
+	}
+
+	o.buf[o.next] = failed
+	if failed {
+		o.failures++
+	}
+	o.next = (o.next + 1) % len(o.buf)
+}
+
+// rate returns the share of failures, and whether the buffer is full enough
+// for it to mean anything.
+func (o *outcomes) rate() (float64, bool) {
+	if len(o.buf) == 0 || o.n < len(o.buf) {
+		return 0, false
+	}
+	return float64(o.failures) / float64(o.n), true
+}
+
+// reset forgets every outcome and resizes the buffer to size.
+func (o *outcomes) reset(size int) {
+	*o = outcomes{buf: make([]bool, max(size, 0))}
+}
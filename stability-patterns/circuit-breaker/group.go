@@ -0,0 +1,187 @@
+package circuitbreaker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/1core-dev/cloud-native/stability-patterns/backoff"
+)
+
+// BreakerGroup manages independent breakers keyed by name, such as one per
+// downstream host, all sharing one configuration.
+//
+// Breakers are created lazily on first use. A breaker that has not been used
+// for the idle duration and is not open is evicted, so the group does not grow
+// without bound as names come and go.
+type BreakerGroup struct {
+	name      string // name in the registry, if registered
+	threshold int
+	idle      time.Duration
+	opts      options
+
+	mu        sync.Mutex
+	breakers  map[string]*groupEntry
+	lastSweep time.Time
+}
+
+// groupEntry is a named breaker together with when it was last used.
+type groupEntry struct {
+	breaker *CircuitBreaker
+	used    time.Time
+}
+
+// NewBreakerGroup returns a BreakerGroup whose breakers open after threshold
+// failures and are evicted after idle without calls. An idle of zero disables
+// eviction.
+//
+// The options apply to every breaker in the group; WithName registers the
+// group as a whole. WithStore is ignored, because a single store cannot hold
+// the state of many breakers; use WithKeyedStore instead.
+func NewBreakerGroup(threshold int, idle time.Duration, opts ...Option) *BreakerGroup {
+	o := buildOptions(opts)
+	o.store = nil
+
+	g := &BreakerGroup{
+		threshold: threshold,
+		idle:      idle,
+		opts:      o,
+		breakers:  make(map[string]*groupEntry),
+		lastSweep: o.clock.Now(),
+	}
+
+	if o.name != "" {
+		g.name = o.name
+		o.registry.RegisterGroup(o.name, g)
+	}
+
+	return g
+}
+
+// Execute runs circuit through the breaker registered under name.
+func (g *BreakerGroup) Execute(ctx context.Context, name string, circuit Circuit) (string, error) {
+	return g.Get(name).Execute(ctx, circuit)
+}
+
+// Wrap returns a Circuit that runs circuit through the breaker registered
+// under name.
+func (g *BreakerGroup) Wrap(name string, circuit Circuit) Circuit {
+	return func(ctx context.Context) (string, error) {
+		return g.Execute(ctx, name, circuit)
+	}
+}
+
+// Len returns the number of breakers currently held by the group.
+func (g *BreakerGroup) Len() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return len(g.breakers)
+}
+
+// Get returns the breaker registered under name, creating it if needed.
+// The returned breaker can be inspected, tripped, or reset.
+func (g *BreakerGroup) Get(name string) *CircuitBreaker {
+	now := g.opts.clock.Now()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.sweep(now)
+
+	e, ok := g.breakers[name]
+	if !ok {
+		e = &groupEntry{breaker: g.newBreaker(name)}
+		g.breakers[name] = e
+	}
+	e.used = now
+
+	return e.breaker
+}
+
+// sweep evicts idle breakers that are not open. It runs at most once per idle
+// period. Callers must hold g.mu.
+func (g *BreakerGroup) sweep(now time.Time) {
+	if g.idle <= 0 || now.Sub(g.lastSweep) < g.idle {
+		return
+	}
+	g.lastSweep = now
+
+	for name, e := range g.breakers {
+		if now.Sub(e.used) < g.idle {
+			continue
+		}
+
+		e.breaker.mu.RLock()
+		state := e.breaker.state(now)
+		e.breaker.mu.RUnlock()
+
+		if state != Open {
+			delete(g.breakers, name)
+		}
+	}
+}
+
+// newBreaker returns a breaker for name, persisted in the keyed store if any.
+func (g *BreakerGroup) newBreaker(name string) *CircuitBreaker {
+	o := g.opts
+	if o.keyedStore != nil {
+		o.store = Named(o.keyedStore, name)
+	}
+
+	cb := newCircuitBreaker(g.threshold, o)
+	if g.name != "" {
+		cb.name, cb.registry = g.name+"/"+name, o.registry
+	}
+
+	return cb
+}
+
+// SetThreshold changes the failure threshold of every breaker in the group,
+// including ones created later.
+func (g *BreakerGroup) SetThreshold(n int) {
+	g.mu.Lock()
+	g.threshold = n
+	breakers := g.list()
+	g.mu.Unlock()
+
+	// Outside g.mu: a breaker opened here notifies listeners and peers
+	for _, cb := range breakers {
+		cb.SetThreshold(n)
+	}
+}
+
+// SetFailureRate changes the failure rate and window of every breaker in the
+// group, including ones created later.
+func (g *BreakerGroup) SetFailureRate(rate float64, window int) {
+	g.mu.Lock()
+	g.opts.failureRate, g.opts.rateWindow = rate, window
+	breakers := g.list()
+	g.mu.Unlock()
+
+	for _, cb := range breakers {
+		cb.SetFailureRate(rate, window)
+	}
+}
+
+// SetBackoff changes the backoff of every breaker in the group, including
+// ones created later.
+func (g *BreakerGroup) SetBackoff(b backoff.Backoff) {
+	g.mu.Lock()
+	g.opts.backoff = b
+	breakers := g.list()
+	g.mu.Unlock()
+
+	for _, cb := range breakers {
+		cb.SetBackoff(b)
+	}
+}
+
+// list returns the breakers currently in the group. Callers must hold g.mu.
+func (g *BreakerGroup) list() []*CircuitBreaker {
+	breakers := make([]*CircuitBreaker, 0, len(g.breakers))
+	for _, e := range g.breakers {
+		breakers = append(breakers, e.breaker)
+	}
+	return breakers
+}
@@ -0,0 +1,58 @@
+package circuitbreaker
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Export writes the state of every breaker in the group as JSON, keyed by
+// name. The group is locked for the duration, so the snapshot is consistent.
+func (g *BreakerGroup) Export(w io.Writer) error {
+	g.mu.Lock()
+
+	snaps := make(map[string]Snapshot, len(g.breakers))
+	for name, e := range g.breakers {
+		e.breaker.mu.RLock()
+		snaps[name] = e.breaker.snapshot()
+		e.breaker.mu.RUnlock()
+	}
+
+	g.mu.Unlock()
+
+	return json.NewEncoder(w).Encode(snaps)
+}
+
+// Import replaces the group's breakers with the ones from a snapshot written
+// by Export. Breakers that were open resume open with their remaining cooldown.
+// The imported state is written to the keyed store, if any, but is not
+// announced as a state change: the breakers are new, not transitioning.
+func (g *BreakerGroup) Import(r io.Reader) error {
+	var snaps map[string]Snapshot
+	if err := json.NewDecoder(r).Decode(&snaps); err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+
+	clear(g.breakers)
+	imported := make([]*CircuitBreaker, 0, len(snaps))
+	for name, snap := range snaps {
+		cb := g.newBreaker(name)
+		cb.mu.Lock()
+		cb.restore(snap)
+		cb.save()
+		cb.observe() // Start from the imported state, as if created in it
+		cb.mu.Unlock()
+
+		g.breakers[name] = &groupEntry{breaker: cb, used: g.opts.clock.Now()}
+		imported = append(imported, cb)
+	}
+
+	g.mu.Unlock()
+
+	for _, cb := range imported {
+		cb.persist()
+	}
+
+	return nil
+}
@@ -0,0 +1,236 @@
+package circuitbreaker
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/1core-dev/cloud-native/stability-patterns/backoff"
+	"github.com/1core-dev/cloud-native/stability-patterns/clock"
+)
+
+// Option configures optional Breaker behavior.
+type Option func(*options)
+
+type options struct {
+	name             string
+	registry         *Registry
+	failureHistory   int
+	store            Store
+	keyedStore       KeyedStore
+	backoff          backoff.Backoff
+	successThreshold int
+	maxProbes        int
+	recoverPanics    bool
+
+	countCancellations bool
+	failureIf          func(error) bool
+
+	failureRate float64 // share of failures that opens the circuit
+	rateWindow  int     // calls the failure rate is judged over
+
+	onStateChange func(name string, from, to State)
+
+	healthCheck    func(context.Context) error
+	healthInterval time.Duration
+
+	warmUp time.Duration
+
+	logger *slog.Logger
+
+	clock clock.Clock
+}
+
+// buildOptions applies opts over the defaults that differ from zero values.
+func buildOptions(opts []Option) options {
+	o := options{
+		failureHistory: DefaultFailureHistory,
+		registry:       DefaultRegistry,
+		clock:          clock.Real,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithStore restores the breaker state from s when the breaker is created and
+// saves it back whenever the failure count changes. Saves run outside the
+// breaker's lock, so a slow store doesn't hold up other calls, and changes
+// made while a save is in progress are coalesced into one more save of the
+// latest state.
+//
+// A breaker that was open before a crash or restart therefore resumes open
+// with its remaining cooldown. Store errors are ignored: the in-memory state
+// stays authoritative and the wrapped call's result is returned unchanged.
+func WithStore(s Store) Option {
+	return func(o *options) {
+		o.store = s
+	}
+}
+
+// WithKeyedStore persists the state of every breaker in a BreakerGroup in ks
+// under the breaker's name, like WithStore does for a single breaker. It has
+// no effect on breakers created with New.
+func WithKeyedStore(ks KeyedStore) Option {
+	return func(o *options) {
+		o.keyedStore = ks
+	}
+}
+
+// WithBackoff sets how long the breaker stays open after each failure past the
+// threshold. Attempt 0 is the failure that first opened the circuit. The
+// default is backoff.Default(), a capped exponential backoff with jitter.
+func WithBackoff(b backoff.Backoff) Option {
+	return func(o *options) {
+		o.backoff = b
+	}
+}
+
+// WithSuccessThreshold sets how many consecutive successful trial calls a
+// half-open breaker needs before it closes. The default is 1. Requiring more
+// keeps a single lucky success from closing the circuit and causing flapping.
+func WithSuccessThreshold(n int) Option {
+	return func(o *options) {
+		o.successThreshold = n
+	}
+}
+
+// WithMaxProbes limits how many trial calls a half-open breaker lets through
+// concurrently; other callers are rejected with ErrServiceUnavailable while
+// the probes are in flight. The default is 1, so a recovering service sees a
+// single request rather than every waiting caller at once.
+func WithMaxProbes(n int) Option {
+	return func(o *options) {
+		o.maxProbes = n
+	}
+}
+
+// WithPanicRecovery makes the breaker recover panics in the wrapped circuit
+// and return them as a *PanicError, counted as a failure. Without it, the
+// breaker still records the failure but then re-panics with the same value.
+func WithPanicRecovery() Option {
+	return func(o *options) {
+		o.recoverPanics = true
+	}
+}
+
+// WithCountCancellations makes the breaker count context.Canceled and
+// context.DeadlineExceeded errors as failures even when they come from the
+// caller's own context being done.
+//
+// By default such errors are ignored, since a caller giving up says nothing
+// about the downstream; counting them can trip the circuit spuriously.
+// Deadline errors while the caller's context is still live always count.
+func WithCountCancellations() Option {
+	return func(o *options) {
+		o.countCancellations = true
+	}
+}
+
+// WithFailureIf counts only the errors for which isFailure returns true as
+// failures; any other error, such as a rejected request that says nothing
+// about the downstream's health, counts as a success. By default every
+// error is a failure. Panics always are.
+func WithFailureIf(isFailure func(error) bool) Option {
+	return func(o *options) {
+		o.failureIf = isFailure
+	}
+}
+
+// WithFailureRate also opens the circuit once failures make up at least
+// rate of the last window calls, consecutive or not, so a downstream failing
+// every other call is caught even though it never reaches the threshold of
+// consecutive failures. The rate is only judged over a full window of calls
+// made while closed, counted afresh each time the circuit opens. A rate of
+// zero or less, or a window of zero or less, disables it, as by default.
+func WithFailureRate(rate float64, window int) Option {
+	return func(o *options) {
+		o.failureRate = rate
+		o.rateWindow = window
+	}
+}
+
+// WithHealthCheck registers a lightweight check that the breaker runs in the
+// background every interval while the circuit is open. As soon as check
+// returns nil, the circuit becomes half-open instead of waiting for the
+// backoff to expire, which shortens recovery after long outages.
+//
+// Each check gets a context that times out after interval, which defaults to
+// one second if not positive. A tripped breaker is not probed.
+func WithHealthCheck(check func(context.Context) error, interval time.Duration) Option {
+	return func(o *options) {
+		o.healthCheck = check
+		o.healthInterval = interval
+	}
+}
+
+// WithFailureHistory sets how many recent failures the breaker keeps for
+// LastFailures. The default is DefaultFailureHistory; 0 disables it.
+func WithFailureHistory(n int) Option {
+	return func(o *options) {
+		o.failureHistory = n
+	}
+}
+
+// WithName registers the breaker (or group) under name in the registry, so
+// its state shows up in the registry's JSON status. Breakers are registered
+// in DefaultRegistry unless WithRegistry is given too.
+func WithName(name string) Option {
+	return func(o *options) {
+		o.name = name
+	}
+}
+
+// WithRegistry sets the registry WithName registers the breaker in.
+func WithRegistry(r *Registry) Option {
+	return func(o *options) {
+		o.registry = r
+	}
+}
+
+// OnStateChange registers fn to be called whenever the breaker moves between
+// states, with the name it was registered under (empty if unnamed). fn runs
+// synchronously on the goroutine that caused the change, so it must be fast.
+func OnStateChange(fn func(name string, from, to State)) Option {
+	return func(o *options) {
+		o.onStateChange = fn
+	}
+}
+
+// WithClock sets the clock the breaker reads the time from and schedules
+// health checks on. The default is clock.Real; tests can pass a *clock.Fake
+// to step through backoffs without sleeping.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// WithWarmUp makes a breaker that has just recovered let traffic back in
+// gradually rather than all at once: over the ramp, the share of calls it
+// lets through grows linearly from none to all, and the rest are rejected
+// with ErrServiceUnavailable. This spares a recovering service with cold
+// caches and fresh connections from instant full load.
+func WithWarmUp(ramp time.Duration) Option {
+	return func(o *options) {
+		o.warmUp = ramp
+	}
+}
+
+// WithLogger sets the logger state changes are logged to: a warning when the
+// circuit opens, and info otherwise. The default is slog.Default(), so
+// slog.SetDefault redirects every breaker without one.
+func WithLogger(l *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// log returns the configured logger, or the default one.
+func (o options) log() *slog.Logger {
+	if o.logger != nil {
+		return o.logger
+	}
+	return slog.Default()
+}
@@ -0,0 +1,165 @@
+package circuitbreaker
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultRegistry is the registry breakers created with WithName join unless
+// another one is set with WithRegistry.
+var DefaultRegistry = &Registry{}
+
+// Registry tracks named breakers and groups so their state can be dumped in
+// one place, such as a /debug/breakers endpoint. The zero value is ready to
+// use.
+type Registry struct {
+	mu       sync.RWMutex
+	breakers map[string]*CircuitBreaker
+	groups   map[string]*BreakerGroup
+
+	listeners    map[int]func(Transition) // replication publishers
+	nextListener int
+}
+
+// Register adds cb under name, replacing any breaker registered before.
+func (r *Registry) Register(name string, cb *CircuitBreaker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.breakers == nil {
+		r.breakers = make(map[string]*CircuitBreaker)
+	}
+	r.breakers[name] = cb
+}
+
+// RegisterGroup adds g under name, replacing any group registered before.
+func (r *Registry) RegisterGroup(name string, g *BreakerGroup) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.groups == nil {
+		r.groups = make(map[string]*BreakerGroup)
+	}
+	r.groups[name] = g
+}
+
+// Unregister removes the breaker or group registered under name.
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.breakers, name)
+	delete(r.groups, name)
+}
+
+// Get returns the breaker registered under name.
+func (r *Registry) Get(name string) (*CircuitBreaker, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cb, ok := r.breakers[name]
+	return cb, ok
+}
+
+// MarshalJSON dumps the status of every registered breaker, and of every
+// breaker in registered groups, keyed by name:
+//
+//	{"breakers": {"db": {...}}, "groups": {"hosts": {"a.example": {...}}}}
+func (r *Registry) MarshalJSON() ([]byte, error) {
+	r.mu.RLock()
+	breakers := maps.Clone(r.breakers)
+	groups := maps.Clone(r.groups)
+	r.mu.RUnlock()
+
+	doc := struct {
+		Breakers map[string]*CircuitBreaker            `json:"breakers"`
+		Groups   map[string]map[string]*CircuitBreaker `json:"groups"`
+	}{
+		Breakers: breakers,
+		Groups:   make(map[string]map[string]*CircuitBreaker, len(groups)),
+	}
+	if doc.Breakers == nil {
+		doc.Breakers = map[string]*CircuitBreaker{}
+	}
+
+	for name, g := range groups {
+		g.mu.Lock()
+		members := make(map[string]*CircuitBreaker, len(g.breakers))
+		for key, e := range g.breakers {
+			members[key] = e.breaker
+		}
+		g.mu.Unlock()
+
+		doc.Groups[name] = members
+	}
+
+	return json.Marshal(doc)
+}
+
+// ServeHTTP writes the registry's JSON status.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	data, err := r.MarshalJSON()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// breakerStatus is the JSON form of a breaker.
+type breakerStatus struct {
+	State        string          `json:"state"`
+	Tripped      bool            `json:"tripped"`
+	OpenUntil    *time.Time      `json:"open_until,omitempty"`
+	Counts       Counts          `json:"counts"`
+	Config       breakerConfig   `json:"config"`
+	LastFailures []failureStatus `json:"last_failures"`
+}
+
+// breakerConfig is the JSON form of a breaker's settings.
+type breakerConfig struct {
+	Threshold        int `json:"threshold"`
+	SuccessThreshold int `json:"success_threshold"`
+	MaxProbes        int `json:"max_probes"`
+}
+
+// failureStatus is the JSON form of a Failure.
+type failureStatus struct {
+	Time    time.Time `json:"time"`
+	Error   string    `json:"error"`
+	Latency string    `json:"latency"`
+}
+
+// MarshalJSON reports the breaker's state, counts, settings, and recent
+// failures.
+func (cb *CircuitBreaker) MarshalJSON() ([]byte, error) {
+	cb.mu.RLock()
+
+	st := breakerStatus{
+		State:   cb.state(cb.opts.clock.Now()).String(),
+		Tripped: cb.tripped,
+		Counts:  cb.counts,
+		Config: breakerConfig{
+			Threshold:        cb.threshold,
+			SuccessThreshold: cb.opts.successThreshold,
+			MaxProbes:        cb.opts.maxProbes,
+		},
+		LastFailures: []failureStatus{},
+	}
+	if !cb.openUntil.IsZero() && cb.counts.ConsecutiveFailures >= cb.threshold {
+		t := cb.openUntil
+		st.OpenUntil = &t
+	}
+	for _, f := range cb.failures.list() {
+		st.LastFailures = append(st.LastFailures, failureStatus{f.Time, f.Err.Error(), f.Latency.String()})
+	}
+
+	cb.mu.RUnlock()
+
+	return json.Marshal(st)
+}
@@ -0,0 +1,175 @@
+package circuitbreaker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Transition is a breaker opening or closing, as broadcast to peer replicas.
+type Transition struct {
+	Name      string    `json:"name"`                 // registry name of the breaker
+	Open      bool      `json:"open"`                 // opened, or closed
+	OpenUntil time.Time `json:"open_until,omitempty"` // backoff expiry when opened
+}
+
+// Remote is a pub/sub transport connecting the registries of several
+// replicas, typically a thin adapter over a Redis client's PUBLISH and
+// SUBSCRIBE.
+type Remote interface {
+	// Publish sends payload to every subscriber of channel.
+	Publish(ctx context.Context, channel string, payload []byte) error
+
+	// Subscribe returns the payloads published to channel until ctx is done.
+	Subscribe(ctx context.Context, channel string) (<-chan []byte, error)
+}
+
+// replicationMessage is the payload exchanged over a Remote.
+type replicationMessage struct {
+	Origin string `json:"origin"` // replica that published the transition
+	Transition
+}
+
+// Replicate shares the transitions of the registry's named breakers with
+// peer replicas over remote on channel, until ctx is done.
+//
+// When a peer's breaker for a dependency opens, the local breaker of the
+// same name opens too, for the same cooldown, so replicas don't all keep
+// hammering a dependency one of them already found down. When the peer's
+// breaker closes again, a local breaker it opened becomes half-open.
+//
+// Replicate returns once the subscription is established. Transitions are
+// published synchronously by the goroutine that caused them, so Publish
+// should be quick; its errors are logged and otherwise ignored.
+func (r *Registry) Replicate(ctx context.Context, remote Remote, channel string) error {
+	msgs, err := remote.Subscribe(ctx, channel)
+	if err != nil {
+		return err
+	}
+
+	var buf [8]byte
+	rand.Read(buf[:])
+	origin := hex.EncodeToString(buf[:])
+
+	remove := r.listen(func(t Transition) {
+		payload, err := json.Marshal(replicationMessage{Origin: origin, Transition: t})
+		if err == nil {
+			err = remote.Publish(ctx, channel, payload)
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "circuit breaker transition not published", "name", t.Name, "error", err)
+		}
+	})
+
+	go func() {
+		defer remove()
+
+		for {
+			select {
+			case payload, ok := <-msgs:
+				if !ok {
+					return
+				}
+
+				var m replicationMessage
+				if err := json.Unmarshal(payload, &m); err != nil || m.Origin == origin {
+					continue // Malformed, or our own transition echoed back
+				}
+				if cb := r.lookup(m.Name); cb != nil {
+					cb.applyRemote(m.Transition)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// listen registers fn to receive every broadcast transition and returns a
+// function removing it.
+func (r *Registry) listen(fn func(Transition)) (remove func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.listeners == nil {
+		r.listeners = make(map[int]func(Transition))
+	}
+
+	id := r.nextListener
+	r.nextListener++
+	r.listeners[id] = fn
+
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		delete(r.listeners, id)
+	}
+}
+
+// broadcast hands t to every listener.
+func (r *Registry) broadcast(t Transition) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, fn := range r.listeners {
+		fn(t)
+	}
+}
+
+// lookup resolves a registry name to a breaker. Names of the form
+// "group/key" resolve to the group's breaker for key, creating it if needed.
+func (r *Registry) lookup(name string) *CircuitBreaker {
+	r.mu.RLock()
+	cb := r.breakers[name]
+	var g *BreakerGroup
+	var key string
+	for gname, grp := range r.groups {
+		if k, ok := strings.CutPrefix(name, gname+"/"); ok {
+			g, key = grp, k
+			break
+		}
+	}
+	r.mu.RUnlock()
+
+	if cb != nil {
+		return cb
+	}
+	if g != nil {
+		return g.Get(key)
+	}
+	return nil
+}
+
+// applyRemote mirrors a peer's transition on the local breaker without
+// broadcasting it again.
+func (cb *CircuitBreaker) applyRemote(t Transition) {
+	cb.mu.Lock()
+
+	now := cb.opts.clock.Now()
+
+	if t.Open {
+		if cb.state(now) != Open && t.OpenUntil.After(now) {
+			cb.counts.ConsecutiveFailures = max(cb.counts.ConsecutiveFailures, cb.threshold)
+			cb.openUntil = t.OpenUntil
+			cb.remote = true
+			cb.save()
+		}
+	} else if cb.remote && !cb.tripped && cb.state(now) == Open {
+		cb.endBackoff(now) // Let a local probe confirm the recovery
+		cb.remote = false
+		cb.save()
+	}
+
+	from, to, changed := cb.observe()
+	cb.mu.Unlock()
+	cb.persist()
+
+	cb.notify(from, to, changed, false)
+}
@@ -0,0 +1,129 @@
+package circuitbreaker
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/1core-dev/cloud-native/manageability-patterns/envelope"
+)
+
+// maxSnapshotSize bounds the size of a snapshot file FileStore will read.
+const maxSnapshotSize = 1 << 16
+
+// Snapshot is the persisted state of a breaker. A breaker restored from a
+// snapshot taken while open stays open for the remainder of its cooldown.
+type Snapshot struct {
+	Failures  int       `json:"failures"`   // consecutive failures
+	OpenUntil time.Time `json:"open_until"` // when the current backoff expires
+	Tripped   bool      `json:"tripped"`    // forced open by Trip
+}
+
+// Store persists breaker state so it survives process restarts.
+type Store interface {
+	// Load returns the last saved snapshot, or a zero Snapshot if none exists.
+	Load() (Snapshot, error)
+
+	// Save records the current snapshot.
+	Save(Snapshot) error
+}
+
+// KeyedStore persists the state of many breakers by name. Implementations
+// backed by a shared store such as Redis let short-lived workers (serverless
+// functions, cron jobs) and replicas pick up each other's breaker state.
+type KeyedStore interface {
+	// Load returns the snapshot saved under name, or a zero Snapshot if none
+	// exists.
+	Load(name string) (Snapshot, error)
+
+	// Save records snap under name.
+	Save(name string, snap Snapshot) error
+}
+
+// Named returns a Store that keeps a single breaker's state in ks under name.
+func Named(ks KeyedStore, name string) Store {
+	return namedStore{ks: ks, name: name}
+}
+
+// namedStore adapts one key of a KeyedStore to a Store.
+type namedStore struct {
+	ks   KeyedStore
+	name string
+}
+
+func (s namedStore) Load() (Snapshot, error)  { return s.ks.Load(s.name) }
+func (s namedStore) Save(snap Snapshot) error { return s.ks.Save(s.name, snap) }
+
+// MemoryStore is a KeyedStore that keeps snapshots in memory. It is useful
+// for sharing state between breakers in one process and in tests.
+type MemoryStore struct {
+	mu    sync.Mutex
+	snaps map[string]Snapshot
+}
+
+// Load returns the snapshot saved under name.
+func (s *MemoryStore) Load(name string) (Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.snaps[name], nil
+}
+
+// Save records snap under name.
+func (s *MemoryStore) Save(name string, snap Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.snaps == nil {
+		s.snaps = make(map[string]Snapshot)
+	}
+	s.snaps[name] = snap
+	return nil
+}
+
+// FileStore is a Store that keeps the snapshot as JSON in a single file,
+// framed in a checksummed envelope so a corrupted file is detected.
+type FileStore struct {
+	path string
+}
+
+// NewFileStore returns a FileStore that reads and writes the file at path.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Load reads the snapshot from the file. A missing file is not an error.
+func (s *FileStore) Load() (Snapshot, error) {
+	var snap Snapshot
+
+	f, err := os.Open(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return snap, nil
+	}
+	if err != nil {
+		return snap, err
+	}
+	defer f.Close()
+
+	data, err := envelope.Decode(f, maxSnapshotSize)
+	if err != nil {
+		return snap, err
+	}
+
+	err = json.Unmarshal(data, &snap)
+	return snap, err
+}
+
+// Save replaces the file with the snapshot atomically and durably, so a
+// crash mid-write never leaves a truncated file behind.
+func (s *FileStore) Save(snap Snapshot) error {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+
+	return envelope.WriteFile(s.path, data, false)
+}
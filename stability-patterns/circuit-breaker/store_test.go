@@ -0,0 +1,110 @@
+package circuitbreaker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/1core-dev/cloud-native/stability-patterns/clock"
+)
+
+// slowStore is a Store whose first Save blocks until released.
+type slowStore struct {
+	mu      sync.Mutex
+	saved   []Snapshot
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (s *slowStore) Load() (Snapshot, error) { return Snapshot{}, nil }
+
+func (s *slowStore) Save(snap Snapshot) error {
+	s.mu.Lock()
+	first := len(s.saved) == 0
+	s.saved = append(s.saved, snap)
+	s.mu.Unlock()
+
+	if first {
+		close(s.entered)
+		<-s.release
+	}
+	return nil
+}
+
+func TestStoreSavesOutsideTheLock(t *testing.T) {
+	store := &slowStore{entered: make(chan struct{}), release: make(chan struct{})}
+	cb := New(10, WithStore(store))
+	fail := func(context.Context) (string, error) { return "", errors.New("fail") }
+
+	slow := make(chan struct{})
+	go func() {
+		cb.Execute(context.Background(), fail)
+		close(slow)
+	}()
+	<-store.entered
+
+	// The first save is stuck: other calls must still go through
+	done := make(chan struct{})
+	go func() {
+		for range 3 {
+			cb.Execute(context.Background(), fail)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("calls blocked behind a slow store")
+	}
+
+	close(store.release)
+	<-slow
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if n := len(store.saved); n != 2 {
+		t.Fatalf("%d saves, want 2: the stuck one, then the coalesced rest", n)
+	}
+	if got := store.saved[1].Failures; got != 4 {
+		t.Fatalf("last save has %d failures, want 4", got)
+	}
+}
+
+func TestGroupImportRestoresQuietly(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	store := new(MemoryStore)
+	var changes []State
+	g := NewBreakerGroup(1, 0, WithClock(fc), WithKeyedStore(store),
+		OnStateChange(func(_ string, _, to State) { changes = append(changes, to) }))
+
+	snap := Snapshot{Failures: 1, OpenUntil: fc.Now().Add(time.Minute)}
+	if err := g.Import(strings.NewReader(`{"db":` + mustJSON(t, snap) + `}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if s := g.Get("db").State(); s != Open {
+		t.Fatalf("imported breaker is %v, want open", s)
+	}
+	failN(t, g.Get("db"), 1) // Rejected while open; must not announce anything
+	if len(changes) != 0 {
+		t.Fatalf("import announced state changes %v, want none", changes)
+	}
+
+	if got, _ := store.Load("db"); !got.OpenUntil.Equal(snap.OpenUntil) || got.Failures != 1 {
+		t.Fatalf("keyed store holds %+v after import, want %+v", got, snap)
+	}
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
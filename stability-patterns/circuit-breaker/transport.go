@@ -0,0 +1,69 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// errServerError marks a 5xx response as a breaker failure.
+var errServerError = errors.New("server error")
+
+// Transport is an http.RoundTripper that applies a circuit breaker per
+// destination host. Transport errors and 5xx responses count as failures.
+//
+// While a host's circuit is open, requests to it fail with
+// ErrServiceUnavailable without being sent. 5xx responses are still returned
+// to the caller unchanged.
+type Transport struct {
+	base  http.RoundTripper
+	group *BreakerGroup
+}
+
+// NewTransport returns a Transport that sends requests through base, using a
+// breaker from group for each host. A nil base uses http.DefaultTransport.
+//
+//	client := &http.Client{Transport: circuitbreaker.NewTransport(nil, group)}
+func NewTransport(base http.RoundTripper, group *BreakerGroup) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &Transport{base: base, group: group}
+}
+
+// RoundTrip sends req unless the circuit for its host is open. A request
+// that is not sent has its body closed, as http.RoundTripper requires.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var (
+		resp *http.Response
+		sent bool // the base transport got req, and with it its body
+	)
+
+	_, err := t.group.Execute(req.Context(), req.URL.Host, func(context.Context) (string, error) {
+		var err error
+
+		sent = true
+		resp, err = t.base.RoundTrip(req)
+		if err != nil {
+			return "", err
+		}
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return "", errServerError
+		}
+
+		return "", nil
+	})
+
+	if errors.Is(err, errServerError) {
+		return resp, nil // Counted as a failure, but the response is still valid
+	}
+	if err != nil {
+		if !sent && req.Body != nil {
+			req.Body.Close() // Rejected by the breaker
+		}
+		return nil, err
+	}
+
+	return resp, nil
+}
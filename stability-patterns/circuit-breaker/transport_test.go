@@ -0,0 +1,60 @@
+package circuitbreaker
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/1core-dev/cloud-native/stability-patterns/backoff"
+)
+
+// closeTracker is a request body that records whether it was closed.
+type closeTracker struct {
+	io.Reader
+	closed bool
+}
+
+func (b *closeTracker) Close() error {
+	b.closed = true
+	return nil
+}
+
+// roundTripFunc adapts a function to an http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestTransportClosesRejectedBodies(t *testing.T) {
+	g := NewBreakerGroup(1, 0, WithBackoff(backoff.Constant{Interval: time.Hour}))
+	base := roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	})
+	tr := NewTransport(base, g)
+
+	if _, err := tr.RoundTrip(newRequest(t)); err == nil {
+		t.Fatal("failing RoundTrip succeeded")
+	}
+
+	req := newRequest(t)
+	if _, err := tr.RoundTrip(req); !errors.Is(err, ErrServiceUnavailable) {
+		t.Fatalf("RoundTrip through an open circuit = %v, want ErrServiceUnavailable", err)
+	}
+	if !req.Body.(*closeTracker).closed {
+		t.Fatal("body of a request rejected by the breaker left open")
+	}
+}
+
+// newRequest returns a POST request whose body tracks closing.
+func newRequest(t *testing.T) *http.Request {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, "http://example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Body = &closeTracker{Reader: strings.NewReader("payload")}
+	return req
+}
@@ -0,0 +1,225 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Fake is a Clock whose time only moves when told to. Timers, tickers, and
+// AfterFunc callbacks fire as Advance or Set moves the time past their
+// deadline. It is safe for concurrent use.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*fakeWaiter
+	added   chan struct{} // closed when a waiter is scheduled
+}
+
+// NewFake returns a Fake set to t.
+func NewFake(t time.Time) *Fake {
+	return &Fake{now: t}
+}
+
+// Now returns the fake time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.now
+}
+
+// Since returns the fake time elapsed since t.
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+// After returns a channel that receives the fake time once d has passed.
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.NewTimer(d).C()
+}
+
+// NewTimer returns a Timer that fires once d has passed.
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	return f.schedule(d, 0, nil)
+}
+
+// AfterFunc calls fn in its own goroutine once d has passed.
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	return f.schedule(d, 0, fn)
+}
+
+// NewTicker returns a Ticker that ticks every d.
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	return fakeTicker{f.schedule(d, d, nil)}
+}
+
+// Advance moves the time forward by d, firing everything that falls due.
+func (f *Fake) Advance(d time.Duration) {
+	f.Set(f.Now().Add(d))
+}
+
+// Set moves the time to t, firing everything that falls due, in deadline
+// order. Moving the time backwards fires nothing.
+func (f *Fake) Set(t time.Time) {
+	for {
+		f.mu.Lock()
+
+		// Fire waiters one at a time, so tickers and callbacks that
+		// schedule more work see the intermediate times
+		sort.Slice(f.waiters, func(i, j int) bool {
+			return f.waiters[i].deadline.Before(f.waiters[j].deadline)
+		})
+
+		if len(f.waiters) == 0 || f.waiters[0].deadline.After(t) {
+			if t.After(f.now) {
+				f.now = t
+			}
+			f.mu.Unlock()
+			return
+		}
+
+		w := f.waiters[0]
+		if w.deadline.After(f.now) {
+			f.now = w.deadline
+		}
+
+		if w.period > 0 {
+			w.deadline = w.deadline.Add(w.period)
+		} else {
+			f.waiters = f.waiters[1:]
+			w.active = false
+		}
+		now := f.now
+		f.mu.Unlock()
+
+		w.fire(now)
+	}
+}
+
+// Waiters returns how many timers and tickers are pending. Tests can use it
+// to wait until the code under test has scheduled its work.
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.waiters)
+}
+
+// BlockUntil blocks until at least n timers and tickers are pending, so a
+// test can be sure the code under test is waiting before it calls Advance.
+func (f *Fake) BlockUntil(n int) {
+	for {
+		f.mu.Lock()
+		if len(f.waiters) >= n {
+			f.mu.Unlock()
+			return
+		}
+		if f.added == nil {
+			f.added = make(chan struct{})
+		}
+		added := f.added
+		f.mu.Unlock()
+
+		<-added
+	}
+}
+
+// schedule registers a waiter due after d, repeating every period if > 0.
+func (f *Fake) schedule(d, period time.Duration, fn func()) *fakeWaiter {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w := &fakeWaiter{
+		clock:    f,
+		deadline: f.now.Add(d),
+		period:   period,
+		fn:       fn,
+		ch:       make(chan time.Time, 1),
+	}
+	f.add(w)
+
+	return w
+}
+
+// add schedules w. Callers must hold f.mu.
+func (f *Fake) add(w *fakeWaiter) {
+	w.active = true
+	f.waiters = append(f.waiters, w)
+
+	if f.added != nil {
+		close(f.added)
+		f.added = nil
+	}
+}
+
+// remove unschedules w and reports whether it was pending. Callers must
+// hold f.mu.
+func (f *Fake) remove(w *fakeWaiter) bool {
+	for i, o := range f.waiters {
+		if o == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			w.active = false
+			return true
+		}
+	}
+	return false
+}
+
+// fakeWaiter implements Timer and Ticker for a Fake.
+type fakeWaiter struct {
+	clock    *Fake
+	deadline time.Time
+	period   time.Duration
+	fn       func()
+	ch       chan time.Time
+	active   bool // guarded by clock.mu
+}
+
+// fire delivers a tick or runs the callback.
+func (w *fakeWaiter) fire(now time.Time) {
+	if w.fn != nil {
+		go w.fn()
+		return
+	}
+
+	select {
+	case w.ch <- now:
+	default: // Drop the tick if the last one wasn't read, like time.Ticker
+	}
+}
+
+func (w *fakeWaiter) C() <-chan time.Time {
+	return w.ch
+}
+
+// Stop prevents the waiter from firing. For timers it reports whether the
+// call stopped a pending timer.
+func (w *fakeWaiter) Stop() bool {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+
+	return w.clock.remove(w)
+}
+
+// Reset reschedules the waiter to fire after d.
+func (w *fakeWaiter) Reset(d time.Duration) bool {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+
+	active := w.clock.remove(w)
+	w.deadline = w.clock.now.Add(d)
+	w.clock.add(w)
+
+	return active
+}
+
+// fakeTicker adapts a repeating fakeWaiter to Ticker.
+type fakeTicker struct{ *fakeWaiter }
+
+func (t fakeTicker) Stop() {
+	t.fakeWaiter.Stop()
+}
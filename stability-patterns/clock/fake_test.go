@@ -0,0 +1,131 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+var epoch = time.Unix(0, 0)
+
+func TestFakeTimer(t *testing.T) {
+	f := NewFake(epoch)
+	timer := f.NewTimer(time.Second)
+
+	f.Advance(999 * time.Millisecond)
+	select {
+	case <-timer.C():
+		t.Fatal("timer fired early")
+	default:
+	}
+
+	f.Advance(time.Millisecond)
+	select {
+	case at := <-timer.C():
+		if !at.Equal(epoch.Add(time.Second)) {
+			t.Fatalf("timer fired at %v, want %v", at, epoch.Add(time.Second))
+		}
+	default:
+		t.Fatal("timer did not fire at its deadline")
+	}
+
+	if timer.Stop() {
+		t.Error("Stop reported a fired timer as pending")
+	}
+}
+
+func TestFakeTimerStopAndReset(t *testing.T) {
+	f := NewFake(epoch)
+	timer := f.NewTimer(time.Second)
+
+	if !timer.Stop() {
+		t.Fatal("Stop reported a pending timer as fired")
+	}
+	f.Advance(time.Hour)
+	select {
+	case <-timer.C():
+		t.Fatal("stopped timer fired")
+	default:
+	}
+
+	timer.Reset(time.Minute) // From the current fake time
+	f.Advance(time.Minute)
+	select {
+	case <-timer.C():
+	default:
+		t.Fatal("reset timer did not fire")
+	}
+}
+
+func TestFakeTicker(t *testing.T) {
+	f := NewFake(epoch)
+	ticker := f.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var ticks []time.Time
+	for range 3 {
+		f.Advance(time.Second)
+		ticks = append(ticks, <-ticker.C())
+	}
+
+	for i, at := range ticks {
+		if want := epoch.Add(time.Duration(i+1) * time.Second); !at.Equal(want) {
+			t.Errorf("tick %d at %v, want %v", i, at, want)
+		}
+	}
+
+	f.Advance(5 * time.Second) // Unread ticks are dropped, as by time.Ticker
+	<-ticker.C()
+	select {
+	case <-ticker.C():
+		t.Error("ticker buffered more than one tick")
+	default:
+	}
+}
+
+func TestFakeAfterFuncOrder(t *testing.T) {
+	f := NewFake(epoch)
+	fired := make(chan time.Duration, 3)
+
+	for _, d := range []time.Duration{3 * time.Second, time.Second, 2 * time.Second} {
+		f.AfterFunc(d, func() { fired <- d })
+	}
+
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+		f.Advance(time.Second)
+		if got := <-fired; got != want {
+			t.Fatalf("callback for %v fired, want %v", got, want)
+		}
+	}
+}
+
+func TestFakeSetBackwards(t *testing.T) {
+	f := NewFake(epoch)
+	timer := f.NewTimer(time.Second)
+
+	f.Set(epoch.Add(-time.Hour))
+	if !f.Now().Equal(epoch) {
+		t.Fatalf("Now after moving back = %v, want %v", f.Now(), epoch)
+	}
+	select {
+	case <-timer.C():
+		t.Fatal("moving the time back fired a timer")
+	default:
+	}
+}
+
+func TestFakeBlockUntil(t *testing.T) {
+	f := NewFake(epoch)
+	done := make(chan struct{})
+
+	go func() {
+		<-f.After(time.Second)
+		close(done)
+	}()
+
+	f.BlockUntil(1)
+	if n := f.Waiters(); n != 1 {
+		t.Fatalf("Waiters = %d, want 1", n)
+	}
+	f.Advance(time.Second)
+	<-done
+}
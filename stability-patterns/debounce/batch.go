@@ -0,0 +1,88 @@
+package debounce
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/1core-dev/cloud-native/stability-patterns/clock"
+)
+
+// DebounceBatch waits until calls stop coming for the duration d, as
+// DebounceLast does, then runs fn once with the arguments of every call in
+// the burst, in arrival order. Each caller gets the result of that run, so
+// it works as a small batcher coalescing writes.
+//
+// The run uses the values of the last call's context but not its
+// cancellation, since the batch holds other callers' work too; a caller
+// whose context ends stops waiting, but its argument stays in the batch.
+// WithMaxWait bounds the size of a batch in time.
+func DebounceBatch[A, R any](fn func(context.Context, []A) (R, error), d time.Duration, opts ...Option) func(context.Context, A) (R, error) {
+	o := buildOptions(opts)
+
+	var (
+		mu    sync.Mutex
+		cur   *batch[A, R] // batch being collected, nil if none
+		timer clock.Timer
+		first time.Time // first call of cur
+	)
+
+	run := func(b *batch[A, R], gen int) {
+		mu.Lock()
+		if cur != b || b.gen != gen {
+			mu.Unlock()
+			return // Rescheduled by a later call
+		}
+		cur = nil
+		o.observe(Event{Kind: Executed, Calls: len(b.args), Age: o.clock.Since(first)})
+		mu.Unlock()
+
+		b.res, b.err = fn(context.WithoutCancel(b.ctx), b.args)
+		close(b.done)
+	}
+
+	return func(ctx context.Context, arg A) (R, error) {
+		mu.Lock()
+
+		now := o.clock.Now()
+		if cur == nil {
+			cur = &batch[A, R]{done: make(chan struct{})}
+			first = now
+		} else {
+			timer.Stop()
+			o.observe(Event{Kind: Suppressed}) // Folded into the batch's run
+		}
+
+		b := cur
+		b.args = append(b.args, arg)
+		b.ctx = ctx
+		b.gen++
+
+		delay := d
+		if o.maxWait > 0 {
+			delay = max(min(delay, first.Add(o.maxWait).Sub(now)), 0)
+		}
+		gen := b.gen
+		timer = o.clock.AfterFunc(delay, func() { run(b, gen) })
+
+		mu.Unlock()
+
+		select {
+		case <-b.done:
+			return b.res, b.err
+		case <-ctx.Done():
+			var zero R
+			return zero, ctx.Err()
+		}
+	}
+}
+
+// batch is the calls collected by DebounceBatch for one run.
+type batch[A, R any] struct {
+	args []A
+	ctx  context.Context // of the latest call
+	gen  int             // bumped by each call, to ignore stale timers
+	done chan struct{}   // closed once res and err are set
+	res  R
+	err  error
+}
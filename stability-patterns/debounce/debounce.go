@@ -1,5 +1,6 @@
 // Package debounce provides wrappers to limit how often a function executes.
-// It ensures that only the first or last call in a burst of calls is processed.
+// It ensures that only the first or last call in a burst of calls is processed,
+// or that the whole burst is processed as one batch.
 package debounce
 
 import (
@@ -9,32 +10,40 @@ import (
 )
 
 // Circuit defines a cancelable operation controlled by debounce logic.
+//
+// The wrappers accept operations returning any type, so cache refreshes,
+// config reloads, or API calls returning structs can be debounced directly;
+// a Circuit is accepted too.
 type Circuit func(context.Context) (string, error)
 
 // DebounceFirst allows only the first call through in a time window.
 // Subsequent calls return cached result.
 //
 // Use when you want immediate response and ignore repeats.
-func DebounceFirst(circuit Circuit, d time.Duration) Circuit {
+func DebounceFirst[T any](circuit func(context.Context) (T, error), d time.Duration, opts ...Option) func(context.Context) (T, error) {
+	o := buildOptions(opts)
+
 	var (
 		threshold time.Time
-		result    string
+		result    T
 		err       error
 		mu        sync.Mutex
 	)
 
-	return func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (T, error) {
 		mu.Lock()
 		defer mu.Unlock()
 
-		if time.Now().Before(threshold) {
+		if o.clock.Now().Before(threshold) {
 			// Suppressed: return cached result
+			o.observe(Event{Kind: Suppressed})
 			return result, err
 		}
 
 		// Executed: store result and delay next execution window
+		o.observe(Event{Kind: Executed, Calls: 1})
 		result, err = circuit(ctx)
-		threshold = time.Now().Add(d)
+		threshold = o.clock.Now().Add(d)
 
 		return result, err
 	}
@@ -43,29 +52,47 @@ func DebounceFirst(circuit Circuit, d time.Duration) Circuit {
 // DebounceFirstContext runs every call but cancels any prior still running.
 //
 // Use when each call has side effects but only one active call at a time is allowed.
-func DebounceFirstContext(circuit Circuit, d time.Duration) Circuit {
+func DebounceFirstContext[T any](circuit func(context.Context) (T, error), d time.Duration, opts ...Option) func(context.Context) (T, error) {
+	o := buildOptions(opts)
+
 	var (
 		threshold  time.Time
 		mu         sync.Mutex
-		lastCtx    context.Context
 		lastCancel context.CancelFunc
+		lastGen    int  // bumped by every call
+		running    bool // the call of lastGen is still running
 	)
 
-	return func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (T, error) {
 		mu.Lock()
 
 		// Cancel prior call in progress
-		if time.Now().Before(threshold) {
+		if o.clock.Now().Before(threshold) {
+			if running {
+				o.observe(Event{Kind: Interrupted})
+			}
 			lastCancel()
 		}
 
 		// Always invoke the function, but reset window
-		lastCtx, lastCancel = context.WithCancel(ctx)
-		threshold = time.Now().Add(d)
+		callCtx, cancel := context.WithCancel(ctx)
+		lastCancel = cancel
+		threshold = o.clock.Now().Add(d)
+		lastGen++
+		gen := lastGen
+		running = true
+		o.observe(Event{Kind: Executed, Calls: 1})
 
 		mu.Unlock()
 
-		result, err := circuit(lastCtx)
+		result, err := circuit(callCtx)
+		cancel()
+
+		mu.Lock()
+		if gen == lastGen {
+			running = false
+		}
+		mu.Unlock()
 
 		return result, err
 	}
@@ -76,46 +103,15 @@ func DebounceFirstContext(circuit Circuit, d time.Duration) Circuit {
 //
 // Use this when you want to wait for a pause in activity
 // before doing something, like waiting for a user to stop typing.
-func DebounceLast(circuit Circuit, d time.Duration) Circuit {
-	var (
-		mu     sync.Mutex
-		timer  *time.Timer
-		cctx   context.Context
-		cancel context.CancelFunc
-	)
-
-	return func(ctx context.Context) (string, error) {
-		mu.Lock()
-
-		// Cancel previous timer and execution
-		if timer != nil {
-			timer.Stop()
-			cancel()
-		}
-
-		// Setup new context for this call
-		cctx, cancel = context.WithCancel(ctx)
-		ch := make(chan struct {
-			result string
-			err    error
-		}, 1)
-
-		// Schedule execution after delay
-		timer = time.AfterFunc(d, func() {
-			r, e := circuit(cctx)
-			ch <- struct {
-				result string
-				err    error
-			}{r, e}
-		})
-
-		mu.Unlock()
+// WithMaxWait bounds the wait under a continuous stream of calls. Use a
+// Debouncer to flush or drop the pending call, as on shutdown.
+func DebounceLast[T any](circuit func(context.Context) (T, error), d time.Duration, opts ...Option) func(context.Context) (T, error) {
+	return New(circuit, d, opts...).Call
+}
 
-		select {
-		case res := <-ch:
-			return res.result, res.err // Call completed
-		case <-cctx.Done():
-			return "", cctx.Err() // Cancelled
-		}
-	}
+// DebounceLastAsync is DebounceLast for callers that must not block: each
+// call returns at once, and its done callback, if not nil, later receives
+// the result, or context.Canceled for the calls superseded by later ones.
+func DebounceLastAsync[T any](circuit func(context.Context) (T, error), d time.Duration, opts ...Option) func(context.Context, func(T, error)) {
+	return New(circuit, d, opts...).CallAsync
 }
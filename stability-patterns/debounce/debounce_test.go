@@ -0,0 +1,152 @@
+package debounce
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/1core-dev/cloud-native/stability-patterns/clock"
+)
+
+func TestDebounceFirst(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	stats := new(Stats)
+	runs := 0
+	f := DebounceFirst(func(context.Context) (int, error) {
+		runs++
+		return runs, nil
+	}, time.Second, WithClock(fc), WithMetrics(stats))
+
+	for range 3 {
+		if v, _ := f(context.Background()); v != 1 {
+			t.Fatalf("call within the window = %d, want the cached 1", v)
+		}
+	}
+
+	fc.Advance(time.Second)
+	if v, _ := f(context.Background()); v != 2 {
+		t.Fatalf("call after the window = %d, want a new run", v)
+	}
+
+	want := Counts{Executed: 2, Suppressed: 2, Coalesced: 2}
+	if got := stats.Counts(); got != want {
+		t.Fatalf("Counts = %+v, want %+v", got, want)
+	}
+}
+
+func TestDebounceFirstContextInterrupts(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	stats := new(Stats)
+	started := make(chan struct{})
+	f := DebounceFirstContext(func(ctx context.Context) (int, error) {
+		if ctx.Value(valueKey{}) == "first" {
+			close(started)
+			<-ctx.Done()
+			return 0, ctx.Err()
+		}
+		return 1, nil
+	}, time.Second, WithClock(fc), WithMetrics(stats))
+
+	first := make(chan error, 1)
+	go func() {
+		_, err := f(context.WithValue(context.Background(), valueKey{}, "first"))
+		first <- err
+	}()
+	<-started
+
+	if v, err := f(context.Background()); v != 1 || err != nil {
+		t.Fatalf("second call = %d, %v; want 1, <nil>", v, err)
+	}
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Fatalf("first call = %v, want context.Canceled", err)
+	}
+
+	want := Counts{Executed: 2, Interrupted: 1, Coalesced: 2}
+	if got := stats.Counts(); got != want {
+		t.Fatalf("Counts = %+v, want %+v", got, want)
+	}
+}
+
+func TestDebounceLastRunsOnceAfterQuiet(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	var (
+		mu   sync.Mutex
+		runs []string
+	)
+	f := DebounceLastAsync(func(ctx context.Context) (string, error) {
+		v := ctx.Value(valueKey{}).(string)
+		mu.Lock()
+		runs = append(runs, v)
+		mu.Unlock()
+		return v, nil
+	}, time.Second, WithClock(fc))
+
+	results := make(chan outcome, 3)
+	for _, v := range []string{"a", "b", "c"} {
+		f(context.WithValue(context.Background(), valueKey{}, v), func(v string, err error) {
+			results <- outcome{v, err}
+		})
+		fc.Advance(500 * time.Millisecond)
+	}
+	fc.Advance(500 * time.Millisecond) // A full window since the last call
+
+	got := map[string]int{}
+	for range 3 {
+		r := <-results
+		if r.err == nil {
+			got[r.value]++
+		}
+	}
+	if len(got) != 1 || got["c"] != 1 {
+		t.Fatalf("completed calls = %v, want only the last one", got)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(runs) != 1 {
+		t.Fatalf("%d runs, want 1", len(runs))
+	}
+}
+
+// valueKey is the context key of the value each call carries in these tests.
+type valueKey struct{}
+
+func TestDebounceBatch(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	stats := new(Stats)
+	f := DebounceBatch(func(_ context.Context, args []int) (int, error) {
+		sum := 0
+		for _, a := range args {
+			sum += a
+		}
+		return sum, nil
+	}, time.Second, WithClock(fc), WithMaxWait(2*time.Second), WithMetrics(stats))
+
+	results := make(chan int, 3)
+	var wg sync.WaitGroup
+	for i := 1; i <= 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, _ := f(context.Background(), i)
+			results <- v
+		}()
+		// Later calls join the batch; wait for each before the next
+		for stats.Counts().Suppressed != uint64(i-1) {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	fc.BlockUntil(1)
+
+	fc.Advance(time.Second)
+	wg.Wait()
+	close(results)
+
+	for v := range results {
+		if v != 6 {
+			t.Fatalf("caller got %d, want the sum of the batch, 6", v)
+		}
+	}
+}
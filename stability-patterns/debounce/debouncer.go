@@ -0,0 +1,177 @@
+package debounce
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/1core-dev/cloud-native/stability-patterns/clock"
+)
+
+// Debouncer runs the last of a burst of calls once calls stop coming for its
+// window, as DebounceLast does, and also lets the owner act on the pending
+// call: Flush runs it at once and Cancel drops it, so that on shutdown
+// pending work is neither lost silently nor left to a timer.
+//
+// It is safe for concurrent use.
+type Debouncer[T any] struct {
+	circuit func(context.Context) (T, error)
+	d       time.Duration
+	opts    options
+
+	mu      sync.Mutex
+	current *call[T]  // latest call, pending or running
+	first   time.Time // first call of the pending burst, zero if none
+	calls   int       // calls in the pending burst
+}
+
+// call is one call to Debouncer.Call.
+type call[T any] struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	timer  clock.Timer
+	fired  bool // the circuit was started for it
+	done   chan result[T]
+}
+
+// result is the outcome of a circuit.
+type result[T any] struct {
+	value T
+	err   error
+}
+
+// New returns a Debouncer running circuit once calls have stopped for d.
+func New[T any](circuit func(context.Context) (T, error), d time.Duration, opts ...Option) *Debouncer[T] {
+	return &Debouncer[T]{circuit: circuit, d: d, opts: buildOptions(opts)}
+}
+
+// Call schedules circuit to run with ctx once calls have stopped for the
+// window, superseding the previous call if it is still pending, and waits
+// for its result. A superseded or canceled call returns context.Canceled. A
+// call whose circuit is already running, as when WithMaxWait forces a run
+// during a stream of calls, is left to finish and returns its result.
+func (db *Debouncer[T]) Call(ctx context.Context) (T, error) {
+	c := db.schedule(ctx)
+	defer c.cancel()
+
+	return c.wait()
+}
+
+// CallAsync is Call without the wait: it schedules the call and returns at
+// once, and done, if not nil, later receives what Call would have returned.
+// Calls are ordered as CallAsync was invoked.
+func (db *Debouncer[T]) CallAsync(ctx context.Context, done func(T, error)) {
+	c := db.schedule(ctx)
+
+	go func() {
+		defer c.cancel()
+
+		v, err := c.wait()
+		if done != nil {
+			done(v, err)
+		}
+	}()
+}
+
+// Flush runs the pending call now, if there is one, without waiting for the
+// window to end, and returns once it has completed.
+func (db *Debouncer[T]) Flush() {
+	db.mu.Lock()
+	c := db.current
+	if c == nil || c.fired {
+		db.mu.Unlock()
+		return
+	}
+	c.timer.Stop()
+	db.mu.Unlock()
+
+	db.fire(c)
+}
+
+// Cancel drops the pending call, if there is one; its caller gets
+// context.Canceled. A call whose circuit is already running is left alone.
+func (db *Debouncer[T]) Cancel() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	c := db.current
+	if c == nil || c.fired {
+		return
+	}
+
+	c.timer.Stop()
+	c.cancel()
+	db.current = nil
+	db.first, db.calls = time.Time{}, 0
+	db.opts.observe(Event{Kind: Canceled})
+}
+
+// schedule makes a call with ctx the pending one, superseding the previous
+// call, and starts its timer.
+func (db *Debouncer[T]) schedule(ctx context.Context) *call[T] {
+	cctx, cancel := context.WithCancel(ctx)
+	c := &call[T]{ctx: cctx, cancel: cancel, done: make(chan result[T], 1)}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	// Supersede the pending call; a running one is left to finish
+	if prev := db.current; prev != nil && !prev.fired {
+		prev.timer.Stop()
+		prev.cancel()
+		db.opts.observe(Event{Kind: Suppressed})
+	}
+
+	// Schedule execution after delay, or by the burst's deadline
+	now := db.opts.clock.Now()
+	if db.first.IsZero() {
+		db.first = now
+	}
+	db.calls++
+	delay := db.d
+	if db.opts.maxWait > 0 {
+		delay = max(min(delay, db.first.Add(db.opts.maxWait).Sub(now)), 0)
+	}
+
+	c.timer = db.opts.clock.AfterFunc(delay, func() { db.fire(c) })
+	db.current = c
+
+	return c
+}
+
+// wait returns the result of c's circuit, or the error of its context.
+func (c *call[T]) wait() (T, error) {
+	select {
+	case res := <-c.done:
+		return res.value, res.err // Call completed
+	case <-c.ctx.Done():
+		var zero T
+		return zero, c.ctx.Err() // Cancelled
+	}
+}
+
+// fire runs the circuit for c, unless it was superseded, dropped, its caller
+// gave up, or it has run already, and delivers the result to its caller.
+func (db *Debouncer[T]) fire(c *call[T]) {
+	db.mu.Lock()
+	if db.current != c || c.fired {
+		db.mu.Unlock()
+		return
+	}
+	if c.ctx.Err() != nil {
+		// The caller gave up; drop the burst
+		db.current = nil
+		db.first, db.calls = time.Time{}, 0
+		db.mu.Unlock()
+		db.opts.observe(Event{Kind: Canceled})
+		return
+	}
+
+	c.fired = true
+	db.opts.observe(Event{Kind: Executed, Calls: db.calls, Age: db.opts.clock.Since(db.first)})
+	db.first, db.calls = time.Time{}, 0 // The next call starts a new burst
+	db.mu.Unlock()
+
+	v, err := db.circuit(c.ctx)
+	c.done <- result[T]{v, err}
+}
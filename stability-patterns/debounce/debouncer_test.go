@@ -0,0 +1,84 @@
+package debounce
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/1core-dev/cloud-native/stability-patterns/clock"
+)
+
+// outcome is what a CallAsync callback received.
+type outcome struct {
+	value string
+	err   error
+}
+
+func TestDebouncerMaxWaitRunSurvivesLaterCalls(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	started, release := make(chan struct{}), make(chan struct{})
+	circuit := func(ctx context.Context) (string, error) {
+		close(started)
+		<-release
+		return "ran", ctx.Err()
+	}
+	db := New(circuit, time.Second, WithClock(fc), WithMaxWait(3*time.Second))
+
+	results := make(chan outcome, 10)
+	call := func() {
+		db.CallAsync(context.Background(), func(v string, err error) { results <- outcome{v, err} })
+	}
+
+	// A call every half window: only maxWait ends the burst
+	for range 6 {
+		call()
+		fc.Advance(500 * time.Millisecond)
+	}
+	<-started
+
+	call() // Must not cancel the run forced by maxWait
+	close(release)
+
+	// Callbacks run on their own goroutines, so results come in any order
+	canceled, ran := 0, 0
+	for range 6 {
+		switch r := <-results; {
+		case errors.Is(r.err, context.Canceled):
+			canceled++
+		case r.value == "ran" && r.err == nil:
+			ran++
+		default:
+			t.Fatalf("call got %q, %v; want context.Canceled or a completed run", r.value, r.err)
+		}
+	}
+	if canceled != 5 || ran != 1 {
+		t.Fatalf("%d calls canceled and %d ran; want the 5 superseded canceled and the forced run completed", canceled, ran)
+	}
+}
+
+func TestDebouncerFlushAndCancel(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	runs := 0
+	db := New(func(context.Context) (int, error) {
+		runs++
+		return runs, nil
+	}, time.Second, WithClock(fc))
+
+	results := make(chan error, 2)
+	db.CallAsync(context.Background(), func(_ int, err error) { results <- err })
+	db.Flush()
+	if err := <-results; err != nil || runs != 1 {
+		t.Fatalf("Flush: err %v, %d runs; want the pending call run once", err, runs)
+	}
+
+	db.CallAsync(context.Background(), func(_ int, err error) { results <- err })
+	db.Cancel()
+	if err := <-results; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Cancel: err %v, want context.Canceled", err)
+	}
+	fc.Advance(time.Second)
+	if runs != 1 {
+		t.Fatalf("canceled call ran: %d runs", runs)
+	}
+}
@@ -0,0 +1,115 @@
+package debounce
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Kind tells what became of a debounced call. A call whose circuit started
+// and was then canceled is reported twice, as Executed and as Interrupted.
+type Kind int
+
+const (
+	Executed    Kind = iota // the circuit ran for it
+	Suppressed              // it was folded into another call's execution
+	Canceled                // it was dropped before its circuit ran
+	Interrupted             // its running circuit was canceled by a later call
+)
+
+// String returns the kind's name.
+func (k Kind) String() string {
+	switch k {
+	case Executed:
+		return "executed"
+	case Suppressed:
+		return "suppressed"
+	case Canceled:
+		return "canceled"
+	case Interrupted:
+		return "interrupted"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes what became of one debounced call.
+type Event struct {
+	Kind Kind
+
+	// For Executed events, Calls is the number of calls of the burst the
+	// execution served, counting its own, and Age how long after the
+	// burst's first call the execution started. Leading-edge wrappers
+	// execute on a burst's first call, so their Calls is 1 and Age 0.
+	Calls int
+	Age   time.Duration
+}
+
+// Metrics receives an Event for the calls of a debounce wrapper configured
+// WithMetrics, to check that its window fits the traffic. Observe is called
+// synchronously, sometimes under the wrapper's lock, so it must be fast.
+type Metrics interface {
+	Observe(Event)
+}
+
+// WithMetrics reports what becomes of calls to m.
+func WithMetrics(m Metrics) Option {
+	return func(o *options) {
+		o.metrics = m
+	}
+}
+
+// observe reports an event to the metrics hook, if any.
+func (o options) observe(e Event) {
+	if o.metrics != nil {
+		o.metrics.Observe(e)
+	}
+}
+
+// Counts holds the totals gathered by Stats.
+type Counts struct {
+	Executed    uint64        `json:"executed"`
+	Suppressed  uint64        `json:"suppressed"`
+	Canceled    uint64        `json:"canceled"`
+	Interrupted uint64        `json:"interrupted"`
+	Coalesced   uint64        `json:"coalesced"` // calls served by executions
+	BurstAge    time.Duration `json:"burst_age"` // total Age of executions
+}
+
+// Stats is a Metrics that keeps running totals. Its zero value is ready to
+// use, and one Stats can be shared by many wrappers.
+type Stats struct {
+	executed    atomic.Uint64
+	suppressed  atomic.Uint64
+	canceled    atomic.Uint64
+	interrupted atomic.Uint64
+	coalesced   atomic.Uint64
+	age         atomic.Int64
+}
+
+// Observe adds e to the totals.
+func (s *Stats) Observe(e Event) {
+	switch e.Kind {
+	case Executed:
+		s.executed.Add(1)
+		s.coalesced.Add(uint64(e.Calls))
+		s.age.Add(int64(e.Age))
+	case Suppressed:
+		s.suppressed.Add(1)
+	case Canceled:
+		s.canceled.Add(1)
+	case Interrupted:
+		s.interrupted.Add(1)
+	}
+}
+
+// Counts returns the current totals.
+func (s *Stats) Counts() Counts {
+	return Counts{
+		Executed:    s.executed.Load(),
+		Suppressed:  s.suppressed.Load(),
+		Canceled:    s.canceled.Load(),
+		Interrupted: s.interrupted.Load(),
+		Coalesced:   s.coalesced.Load(),
+		BurstAge:    time.Duration(s.age.Load()),
+	}
+}
@@ -0,0 +1,43 @@
+package debounce
+
+import (
+	"time"
+
+	"github.com/1core-dev/cloud-native/stability-patterns/clock"
+)
+
+// Option configures optional debounce behavior.
+type Option func(*options)
+
+type options struct {
+	clock   clock.Clock
+	maxWait time.Duration
+	metrics Metrics
+}
+
+// buildOptions applies opts over the defaults.
+func buildOptions(opts []Option) options {
+	o := options{clock: clock.Real}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithClock sets the clock that measures the debounce window. The default is
+// clock.Real; tests can pass a *clock.Fake to end a window without sleeping.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// WithMaxWait caps how long DebounceLast may put off execution: once d has
+// passed since the first call of a burst, the latest call runs even if calls
+// keep arriving, as with lodash's maxWait. By default a continuous stream of
+// calls defers execution indefinitely.
+func WithMaxWait(d time.Duration) Option {
+	return func(o *options) {
+		o.maxWait = d
+	}
+}
@@ -0,0 +1,247 @@
+// Package dispatch queues outgoing calls to a shared downstream and sends
+// them in order of priority and age, at most a limited number at a time.
+//
+// When a dependency slows down, calls pile up faster than they complete.
+// Sending them FIFO makes every caller wait behind whatever arrived first;
+// a dispatch Queue sends the most important ones first instead, while aging
+// keeps low-priority calls from waiting forever.
+//
+// Priorities are read from the call's context, as set by
+// throttle.WithPriority.
+package dispatch
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/1core-dev/cloud-native/stability-patterns/clock"
+	"github.com/1core-dev/cloud-native/stability-patterns/throttle"
+)
+
+// ErrQueueFull is returned when a call cannot be queued because the queue
+// already holds its maximum number of waiting calls.
+var ErrQueueFull = errors.New("dispatch queue full")
+
+// Func is the shape of the calls a Queue wraps.
+type Func func(context.Context) (string, error)
+
+// Option configures optional Queue behavior.
+type Option func(*options)
+
+type options struct {
+	clock    clock.Clock
+	aging    time.Duration
+	maxQueue int
+}
+
+// WithClock sets the clock used to age waiting calls. The default is
+// clock.Real.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// WithAging raises the effective priority of a waiting call by one level
+// for every step it has waited, so a steady stream of important calls
+// cannot starve the others. By default calls are ordered by priority only,
+// and by arrival within a priority.
+func WithAging(step time.Duration) Option {
+	return func(o *options) {
+		o.aging = step
+	}
+}
+
+// WithMaxQueue bounds the number of waiting calls; further calls fail with
+// ErrQueueFull. By default the queue is unbounded.
+func WithMaxQueue(n int) Option {
+	return func(o *options) {
+		o.maxQueue = n
+	}
+}
+
+// Stats is a snapshot of a Queue.
+type Stats struct {
+	Limit    int // calls allowed in flight
+	InFlight int // calls in flight
+	Queued   int // calls waiting
+}
+
+// Queue admits calls to a downstream up to a concurrency limit, and queues
+// the rest in order of priority and age.
+//
+// The limit can be changed at any time with SetLimit, so a concurrency
+// limiter that adapts to the downstream's latency can drive it.
+type Queue struct {
+	opts options
+
+	mu       sync.Mutex
+	limit    int
+	inFlight int
+	waiters  waitHeap
+	seq      uint64 // arrival counter for FIFO order among equal ranks
+}
+
+// New returns a Queue allowing limit calls in flight at once.
+func New(limit int, opts ...Option) *Queue {
+	o := options{clock: clock.Real}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &Queue{opts: o, limit: max(limit, 1)}
+}
+
+// Acquire waits for a slot, ranked by the priority in ctx, and returns the
+// function that releases it once the call is done. It returns ErrQueueFull
+// if the queue is full, or ctx.Err() if ctx is done before a slot is free.
+func (q *Queue) Acquire(ctx context.Context) (release func(), err error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	release = sync.OnceFunc(q.release)
+
+	q.mu.Lock()
+
+	// Fast path: a slot is free and nobody is waiting for it
+	if q.inFlight < q.limit && len(q.waiters) == 0 {
+		q.inFlight++
+		q.mu.Unlock()
+		return release, nil
+	}
+
+	if q.opts.maxQueue > 0 && len(q.waiters) >= q.opts.maxQueue {
+		q.mu.Unlock()
+		return nil, ErrQueueFull
+	}
+
+	w := &waiter{rank: q.rank(throttle.PriorityFromContext(ctx)), seq: q.seq, ready: make(chan struct{})}
+	q.seq++
+	heap.Push(&q.waiters, w)
+
+	q.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return release, nil
+	case <-ctx.Done():
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if w.index < 0 {
+		return release, nil // Granted while we were giving up; keep the slot
+	}
+
+	heap.Remove(&q.waiters, w.index)
+	return nil, ctx.Err()
+}
+
+// Wrap returns a Func that holds a slot of the queue while calling fn.
+func (q *Queue) Wrap(fn Func) Func {
+	return func(ctx context.Context) (string, error) {
+		release, err := q.Acquire(ctx)
+		if err != nil {
+			return "", err
+		}
+		defer release()
+
+		return fn(ctx)
+	}
+}
+
+// SetLimit changes the number of calls allowed in flight. Raising it sends
+// waiting calls at once; lowering it lets calls in flight finish.
+func (q *Queue) SetLimit(n int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.limit = max(n, 1)
+	q.dispatch()
+}
+
+// Stats returns the current state of the queue.
+func (q *Queue) Stats() Stats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return Stats{Limit: q.limit, InFlight: q.inFlight, Queued: len(q.waiters)}
+}
+
+// release frees a slot and hands it to the best waiting call.
+func (q *Queue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.inFlight--
+	q.dispatch()
+}
+
+// dispatch hands free slots to waiters in rank order. Callers must hold
+// q.mu.
+func (q *Queue) dispatch() {
+	for q.inFlight < q.limit && len(q.waiters) > 0 {
+		w := heap.Pop(&q.waiters).(*waiter)
+		q.inFlight++
+		close(w.ready)
+	}
+}
+
+// rank returns the ordering key of a call of priority p arriving now.
+//
+// With aging, a call's effective priority is p plus the number of steps it
+// has waited. Comparing p1 + (now-t1)/step with p2 + (now-t2)/step does not
+// depend on now, so the key p*step - t can be fixed on arrival.
+func (q *Queue) rank(p throttle.Priority) int64 {
+	if q.opts.aging <= 0 {
+		return int64(p)
+	}
+
+	return int64(p)*int64(q.opts.aging) - q.opts.clock.Now().UnixNano()
+}
+
+// waiter is a call queued for a slot.
+type waiter struct {
+	rank  int64
+	seq   uint64
+	ready chan struct{} // closed when a slot is granted
+	index int           // position in the heap, -1 once removed
+}
+
+// waitHeap orders waiters by rank, highest first, then by arrival.
+type waitHeap []*waiter
+
+func (h waitHeap) Len() int { return len(h) }
+
+func (h waitHeap) Less(i, j int) bool {
+	if h[i].rank != h[j].rank {
+		return h[i].rank > h[j].rank
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h waitHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *waitHeap) Push(x any) {
+	w := x.(*waiter)
+	w.index = len(*h)
+	*h = append(*h, w)
+}
+
+func (h *waitHeap) Pop() any {
+	old := *h
+	n := len(old)
+	w := old[n-1]
+	w.index = -1
+	*h = old[:n-1]
+	return w
+}
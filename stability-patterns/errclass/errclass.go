@@ -0,0 +1,204 @@
+// Package errclass sorts errors into a few standard classes, so that retry
+// policies, circuit breakers, and fallbacks share one notion of which errors
+// are worth retrying or say something about a downstream's health.
+//
+// Errors are classified by an explicit wrapper, by the class a type
+// declares, or by recognizing standard errors:
+//
+//	err = errclass.AsThrottled(err)           // wrap explicitly
+//	errclass.Of(err)                          // errclass.Throttled
+//	errclass.FromHTTPStatus(resp.StatusCode)  // for responses
+//
+//	retry.Retry(call, 3, time.Second, retry.WithRetryIf(errclass.Retryable))
+//	circuitbreaker.New(5, circuitbreaker.WithFailureIf(errclass.IsFailure))
+package errclass
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+)
+
+// Class is the category of an error.
+type Class int
+
+const (
+	Unknown   Class = iota // not classified
+	Transient              // a passing failure, such as a dropped connection; retry
+	Throttled              // the downstream asked to slow down; retry later
+	Timeout                // the call ran out of time; retry if idempotent
+	Canceled               // the caller gave up; don't retry
+	Permanent              // retrying cannot help, such as invalid input
+)
+
+// String returns the class's name.
+func (c Class) String() string {
+	switch c {
+	case Transient:
+		return "transient"
+	case Throttled:
+		return "throttled"
+	case Timeout:
+		return "timeout"
+	case Canceled:
+		return "canceled"
+	case Permanent:
+		return "permanent"
+	default:
+		return "unknown"
+	}
+}
+
+// Error is an error tagged with its class.
+type Error struct {
+	Class Class
+	Err   error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrorClass returns e.Class, making an *Error a Classer.
+func (e *Error) ErrorClass() Class {
+	return e.Class
+}
+
+// Wrap tags err with class c. It returns nil if err is nil.
+func Wrap(c Class, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Class: c, Err: err}
+}
+
+// AsTransient tags err as Transient.
+func AsTransient(err error) error { return Wrap(Transient, err) }
+
+// AsThrottled tags err as Throttled.
+func AsThrottled(err error) error { return Wrap(Throttled, err) }
+
+// AsTimeout tags err as Timeout.
+func AsTimeout(err error) error { return Wrap(Timeout, err) }
+
+// AsCanceled tags err as Canceled.
+func AsCanceled(err error) error { return Wrap(Canceled, err) }
+
+// AsPermanent tags err as Permanent.
+func AsPermanent(err error) error { return Wrap(Permanent, err) }
+
+// Classer is implemented by errors that know their own class.
+type Classer interface {
+	ErrorClass() Class
+}
+
+// Of returns the class of err. The outermost Classer in its chain, such as
+// an *Error, decides; otherwise context errors, network timeouts, and connection
+// failures are recognized. Of(nil) is Unknown.
+func Of(err error) Class {
+	if err == nil {
+		return Unknown
+	}
+
+	var c Classer
+	if errors.As(err, &c) {
+		return c.ErrorClass()
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return Timeout
+	}
+
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return Timeout
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.EPIPE) {
+		return Transient
+	}
+
+	var op *net.OpError
+	if errors.As(err, &op) {
+		return Transient // Other network failures, such as unreachable hosts
+	}
+
+	return Unknown
+}
+
+// FromHTTPStatus returns the class of an HTTP response status. Success and
+// redirect statuses are Unknown, since they are not errors.
+//
+// Every 5xx status but 501 and 505 is Transient, since it says the
+// downstream is unwell. That is broader than retry.DefaultRetryStatuses,
+// which leaves out 408 and 500 since resending the same request rarely
+// helps them.
+func FromHTTPStatus(code int) Class {
+	switch {
+	case code == 429:
+		return Throttled
+	case code == 408 || code == 504:
+		return Timeout
+	case code == 499: // Client closed request
+		return Canceled
+	case code == 501 || code == 505:
+		return Permanent // Not implemented, version not supported
+	case code >= 500 && code < 600:
+		return Transient
+	case code >= 400 && code < 500:
+		return Permanent
+	default:
+		return Unknown
+	}
+}
+
+// FromGRPCCode returns the class of a gRPC status code, given as its
+// numeric value so that this package doesn't depend on gRPC. OK and codes
+// whose meaning depends on the service (Unknown, Internal, DataLoss) are
+// Unknown.
+func FromGRPCCode(code uint32) Class {
+	switch code {
+	case 1: // Canceled
+		return Canceled
+	case 4: // DeadlineExceeded
+		return Timeout
+	case 8: // ResourceExhausted
+		return Throttled
+	case 10, 14: // Aborted, Unavailable
+		return Transient
+	case 3, 5, 6, 7, 9, 11, 12, 16:
+		// InvalidArgument, NotFound, AlreadyExists, PermissionDenied,
+		// FailedPrecondition, OutOfRange, Unimplemented, Unauthenticated
+		return Permanent
+	default:
+		return Unknown
+	}
+}
+
+// Retryable reports whether err is worth retrying: every class but Canceled
+// and Permanent. Unknown errors are retried, as retry.Retry does by default.
+// It suits retry.WithRetryIf and deciding whether to fail over.
+func Retryable(err error) bool {
+	switch Of(err) {
+	case Canceled, Permanent:
+		return false
+	default:
+		return true
+	}
+}
+
+// IsFailure reports whether err reflects on the downstream's health, and so
+// should count towards opening a circuit breaker. Canceled and Permanent
+// errors, such as the caller giving up or rejected input, do not.
+func IsFailure(err error) bool {
+	return err != nil && Retryable(err)
+}
@@ -0,0 +1,237 @@
+// Package policy declares resilience policies (timeout, retry, circuit breaker
+// and throttle settings) by name in a configuration document, instead of
+// scattering constructor calls through the code.
+//
+// Policies are loaded into a Registry from JSON, validated, and filled in with
+// defaults. A Registry can watch its file and swap in new policies without
+// restarting the service.
+//
+//	{
+//	  "payments": {
+//	    "timeout":  "2s",
+//	    "retry":    {"max_retries": 3, "delay": "100ms"},
+//	    "breaker":  {"threshold": 5},
+//	    "throttle": {"max": 100, "refill": 10, "interval": "1s"}
+//	  },
+//	  "critical-db": {
+//	    "extends": "payments",
+//	    "retry":    {"max_retries": 5},
+//	    "throttle": null
+//	  }
+//	}
+//
+// A policy can extend another one, such as a shared profile, and override
+// only the knobs that differ; the rest is inherited. Policies are looked up
+// by the name of the dependency they protect.
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	circuitbreaker "github.com/1core-dev/cloud-native/stability-patterns/circuit-breaker"
+	"github.com/1core-dev/cloud-native/stability-patterns/resilience"
+)
+
+// Defaults applied to fields left unset in a policy section.
+const (
+	DefaultRetryDelay       = 100 * time.Millisecond
+	DefaultBreakerThreshold = 5
+	DefaultThrottleRefill   = 1
+	DefaultThrottleInterval = time.Second
+)
+
+// Func is the shape of the operations policies wrap.
+type Func func(context.Context) (string, error)
+
+// Duration is a time.Duration that is written in JSON as a string such as
+// "250ms" or "2s".
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalJSON formats the duration as a string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// RetrySpec configures the retry layer.
+type RetrySpec struct {
+	MaxRetries int      `json:"max_retries"`
+	Delay      Duration `json:"delay"`
+}
+
+// BreakerSpec configures the circuit breaker layer.
+type BreakerSpec struct {
+	Threshold int `json:"threshold"`
+}
+
+// ThrottleSpec configures the token bucket layer.
+type ThrottleSpec struct {
+	Max      uint     `json:"max"`
+	Refill   uint     `json:"refill"`
+	Interval Duration `json:"interval"`
+}
+
+// Spec declares a policy. Every section is optional; a missing section
+// disables that layer.
+//
+// Extends names a base policy whose knobs this one inherits: its own knobs
+// are merged over the base's, section by section, and a null knob or
+// section removes the inherited one. Bases may extend others in turn.
+type Spec struct {
+	Extends string `json:"extends,omitempty"`
+
+	Timeout  Duration      `json:"timeout,omitempty"`
+	Retry    *RetrySpec    `json:"retry,omitempty"`
+	Breaker  *BreakerSpec  `json:"breaker,omitempty"`
+	Throttle *ThrottleSpec `json:"throttle,omitempty"`
+}
+
+// withDefaults returns a copy of s with unset fields filled in.
+func (s Spec) withDefaults() Spec {
+	if s.Retry != nil {
+		r := *s.Retry
+		if r.Delay == 0 {
+			r.Delay = Duration(DefaultRetryDelay)
+		}
+		s.Retry = &r
+	}
+
+	if s.Breaker != nil {
+		b := *s.Breaker
+		if b.Threshold == 0 {
+			b.Threshold = DefaultBreakerThreshold
+		}
+		s.Breaker = &b
+	}
+
+	if s.Throttle != nil {
+		t := *s.Throttle
+		if t.Refill == 0 {
+			t.Refill = DefaultThrottleRefill
+		}
+		if t.Interval == 0 {
+			t.Interval = Duration(DefaultThrottleInterval)
+		}
+		s.Throttle = &t
+	}
+
+	return s
+}
+
+// Validate reports every invalid setting in s.
+func (s Spec) Validate() error {
+	var errs []error
+
+	if s.Timeout < 0 {
+		errs = append(errs, errors.New("timeout must not be negative"))
+	}
+	if s.Retry != nil {
+		if s.Retry.MaxRetries < 0 {
+			errs = append(errs, errors.New("retry.max_retries must not be negative"))
+		}
+		if s.Retry.Delay < 0 {
+			errs = append(errs, errors.New("retry.delay must not be negative"))
+		}
+	}
+	if s.Breaker != nil && s.Breaker.Threshold < 1 {
+		errs = append(errs, errors.New("breaker.threshold must be at least 1"))
+	}
+	if s.Throttle != nil {
+		if s.Throttle.Max < 1 {
+			errs = append(errs, errors.New("throttle.max must be at least 1"))
+		}
+		if s.Throttle.Interval <= 0 {
+			errs = append(errs, errors.New("throttle.interval must be positive"))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Policy is a validated Spec ready to wrap functions. It owns a circuit
+// breaker, if configured, that is shared by every function it wraps.
+type Policy struct {
+	Name string
+	Spec Spec
+
+	breaker *circuitbreaker.CircuitBreaker
+}
+
+// newPolicy validates spec and builds the policy. If prev, the policy of
+// the same name being replaced, has the same settings, it is returned
+// instead; otherwise its breaker is carried over if it is configured alike.
+func newPolicy(name string, spec Spec, prev *Policy) (*Policy, error) {
+	spec = spec.withDefaults()
+	if err := spec.Validate(); err != nil {
+		return nil, fmt.Errorf("policy %q: %w", name, err)
+	}
+
+	if prev != nil && sameSettings(prev.Spec, spec) {
+		return prev, nil
+	}
+
+	p := &Policy{Name: name, Spec: spec}
+	switch {
+	case spec.Breaker == nil:
+	case prev != nil && prev.breaker != nil && *prev.Spec.Breaker == *spec.Breaker:
+		p.breaker = prev.breaker // Keep its state across the reload
+	default:
+		p.breaker = circuitbreaker.New(spec.Breaker.Threshold)
+	}
+
+	return p, nil
+}
+
+// sameSettings reports whether a and b configure the same layers, whatever
+// policies they were inherited from.
+func sameSettings(a, b Spec) bool {
+	a.Extends, b.Extends = "", ""
+	return reflect.DeepEqual(a, b)
+}
+
+// Breaker returns the policy's circuit breaker, or nil if it has none.
+func (p *Policy) Breaker() *circuitbreaker.CircuitBreaker {
+	return p.breaker
+}
+
+// Wrap applies the policy's layers to fn, outermost first: timeout, retry,
+// circuit breaker, throttle. The timeout bounds the whole call including
+// retries. Each call to Wrap gets its own throttle bucket.
+func (p *Policy) Wrap(fn Func) Func {
+	var layers []resilience.Layer
+
+	if d := time.Duration(p.Spec.Timeout); d > 0 {
+		layers = append(layers, resilience.Timeout(d))
+	}
+	if r := p.Spec.Retry; r != nil {
+		layers = append(layers, resilience.Retry(r.MaxRetries, time.Duration(r.Delay)))
+	}
+	if p.breaker != nil {
+		layers = append(layers, resilience.BreakerOf(p.breaker))
+	}
+	if t := p.Spec.Throttle; t != nil {
+		layers = append(layers, resilience.Throttle(t.Max, t.Refill, time.Duration(t.Interval)))
+	}
+
+	return Func(resilience.Chain(resilience.Func(fn), layers...))
+}
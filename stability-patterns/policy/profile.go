@@ -0,0 +1,106 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// resolve decodes the policy documents in raw into Specs, applying
+// inheritance: a document with "extends" is merged over its parent's
+// resolved document, knob by knob, so it only states what it overrides. A
+// null value removes the inherited knob or section.
+func resolve(raw map[string]json.RawMessage) (map[string]Spec, error) {
+	docs := make(map[string]map[string]any, len(raw))
+	for name, r := range raw {
+		var doc map[string]any
+		if err := json.Unmarshal(r, &doc); err != nil {
+			return nil, fmt.Errorf("policy %q: %w", name, err)
+		}
+		docs[name] = doc
+	}
+
+	resolved := make(map[string]map[string]any, len(docs))
+
+	var merge func(name string, chain []string) (map[string]any, error)
+	merge = func(name string, chain []string) (map[string]any, error) {
+		if doc, ok := resolved[name]; ok {
+			return doc, nil
+		}
+
+		for _, n := range chain {
+			if n == name {
+				return nil, fmt.Errorf("policy %q: inheritance cycle %s", chain[0], strings.Join(append(chain, name), " -> "))
+			}
+		}
+		chain = append(chain, name)
+
+		doc := docs[name]
+		parent, _ := doc["extends"].(string)
+		if parent != "" {
+			if _, ok := docs[parent]; !ok {
+				return nil, fmt.Errorf("policy %q: extends unknown policy %q", name, parent)
+			}
+
+			base, err := merge(parent, chain)
+			if err != nil {
+				return nil, err
+			}
+			doc = mergeDoc(base, doc)
+		}
+
+		resolved[name] = doc
+		return doc, nil
+	}
+
+	specs := make(map[string]Spec, len(docs))
+	for name := range docs {
+		doc, err := merge(name, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := json.Marshal(doc)
+		if err != nil {
+			return nil, fmt.Errorf("policy %q: %w", name, err)
+		}
+
+		var spec Spec
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields() // Catch typos in knob names
+		if err := dec.Decode(&spec); err != nil {
+			return nil, fmt.Errorf("policy %q: %w", name, err)
+		}
+		specs[name] = spec
+	}
+
+	return specs, nil
+}
+
+// mergeDoc returns a copy of base with override merged over it, in the
+// manner of a JSON merge patch (RFC 7386): nested objects are merged, null
+// removes a key, and any other value replaces it.
+func mergeDoc(base, override map[string]any) map[string]any {
+	merged := make(map[string]any, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+
+	for k, v := range override {
+		switch v := v.(type) {
+		case nil:
+			delete(merged, k)
+		case map[string]any:
+			if b, ok := merged[k].(map[string]any); ok {
+				merged[k] = mergeDoc(b, v)
+			} else {
+				merged[k] = mergeDoc(nil, v) // Drop nulls inside
+			}
+		default:
+			merged[k] = v
+		}
+	}
+
+	return merged
+}
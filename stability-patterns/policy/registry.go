@@ -0,0 +1,140 @@
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Registry holds the currently loaded policies by name. It is safe for
+// concurrent use and can be reloaded while in use.
+type Registry struct {
+	policies atomic.Pointer[map[string]*Policy]
+}
+
+// Load parses a JSON document mapping policy names to Specs and, if every
+// policy is valid, replaces the registry's policies with it. On error the
+// current policies stay in place.
+//
+// A policy may extend another one by name and override some of its knobs;
+// see Spec.Extends.
+//
+// Policies whose resolved settings are unchanged are kept as they are, so a
+// reload leaves their circuit breakers, and the throttle buckets of wrappers
+// built from them, in their current state. A changed policy keeps its
+// breaker if the breaker's own settings are unchanged.
+func (reg *Registry) Load(r io.Reader) error {
+	var raw map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return fmt.Errorf("parse policies: %w", err)
+	}
+
+	specs, err := resolve(raw)
+	if err != nil {
+		return fmt.Errorf("parse policies: %w", err)
+	}
+
+	var current map[string]*Policy
+	if m := reg.policies.Load(); m != nil {
+		current = *m
+	}
+
+	policies := make(map[string]*Policy, len(specs))
+	for name, spec := range specs {
+		p, err := newPolicy(name, spec, current[name])
+		if err != nil {
+			return err
+		}
+		policies[name] = p
+	}
+
+	reg.policies.Store(&policies)
+	return nil
+}
+
+// LoadFile loads the policies from the JSON file at path.
+func (reg *Registry) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return reg.Load(bytes.NewReader(data))
+}
+
+// Get returns the current policy named name.
+func (reg *Registry) Get(name string) (*Policy, bool) {
+	m := reg.policies.Load()
+	if m == nil {
+		return nil, false
+	}
+
+	p, ok := (*m)[name]
+	return p, ok
+}
+
+// Wrap returns fn wrapped by the policy named name. The policy is looked up
+// on every call, so a reload takes effect without re-wrapping. While no
+// policy of that name is loaded, fn is called unwrapped.
+func (reg *Registry) Wrap(name string, fn Func) Func {
+	var (
+		mu      sync.Mutex
+		current *Policy // policy the cached wrapper was built from
+		wrapped = fn
+	)
+
+	return func(ctx context.Context) (string, error) {
+		p, _ := reg.Get(name)
+
+		mu.Lock()
+		if p != current {
+			current = p
+			wrapped = fn
+			if p != nil {
+				wrapped = p.Wrap(fn)
+			}
+		}
+		w := wrapped
+		mu.Unlock()
+
+		return w(ctx)
+	}
+}
+
+// WatchFile reloads the policies from path whenever its modification time
+// changes, polling every interval until ctx is done. Invalid documents are
+// logged and ignored, leaving the previous policies in place.
+func (reg *Registry) WatchFile(ctx context.Context, path string, interval time.Duration) {
+	var last time.Time
+
+	if fi, err := os.Stat(path); err == nil {
+		last = fi.ModTime()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fi, err := os.Stat(path)
+			if err != nil || !fi.ModTime().After(last) {
+				continue
+			}
+			last = fi.ModTime()
+
+			if err := reg.LoadFile(path); err != nil {
+				log.Printf("policy: reload of %s failed: %v", path, err)
+			}
+		}
+	}
+}
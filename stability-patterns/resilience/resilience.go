@@ -0,0 +1,129 @@
+// Package resilience composes the stability wrappers (timeout, retry, circuit
+// breaker, throttle) around a function in a declared order, replacing deeply
+// nested wrapper calls with a flat list.
+//
+//	call := resilience.Chain(fetch,
+//		resilience.Timeout(2*time.Second),
+//		resilience.Retry(3, 100*time.Millisecond),
+//		resilience.Breaker(5),
+//		resilience.Throttle(100, 10, time.Second),
+//	)
+package resilience
+
+import (
+	"context"
+	"time"
+
+	circuitbreaker "github.com/1core-dev/cloud-native/stability-patterns/circuit-breaker"
+	"github.com/1core-dev/cloud-native/stability-patterns/retry"
+	"github.com/1core-dev/cloud-native/stability-patterns/throttle"
+	"github.com/1core-dev/cloud-native/stability-patterns/timeout"
+)
+
+// Func is the shape shared by circuitbreaker.Circuit, retry.Effector and
+// throttle.Effector.
+type Func func(context.Context) (string, error)
+
+// Layer wraps a Func with one stability pattern.
+type Layer func(Func) Func
+
+// Chain wraps fn with layers. The first layer is the outermost: it sees a
+// call first and its result last.
+func Chain(fn Func, layers ...Layer) Func {
+	for i := len(layers) - 1; i >= 0; i-- {
+		fn = layers[i](fn)
+	}
+
+	return fn
+}
+
+// Timeout bounds every call with a deadline of d. The caller stops waiting
+// when it passes, even if the wrapped function ignores its context.
+func Timeout(d time.Duration) Layer {
+	return func(next Func) Func {
+		return func(ctx context.Context) (string, error) {
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+
+			slow := timeout.Timeout(func(string) (string, error) {
+				return next(ctx)
+			})
+
+			return slow(ctx, "")
+		}
+	}
+}
+
+// Retry retries failed calls up to maxRetries times, waiting delay between
+// attempts. See retry.Retry.
+func Retry(maxRetries int, delay time.Duration) Layer {
+	return func(next Func) Func {
+		return Func(retry.Retry(retry.Effector(next), maxRetries, delay))
+	}
+}
+
+// Breaker stops calls after threshold consecutive failures. Each chain the
+// layer is applied to gets its own breaker. See circuitbreaker.Breaker.
+func Breaker(threshold int, opts ...circuitbreaker.Option) Layer {
+	return func(next Func) Func {
+		return Func(circuitbreaker.Breaker(circuitbreaker.Circuit(next), threshold, opts...))
+	}
+}
+
+// BreakerOf runs calls through an existing breaker, so it can be shared or
+// inspected.
+func BreakerOf(cb *circuitbreaker.CircuitBreaker) Layer {
+	return func(next Func) Func {
+		return Func(cb.Wrap(circuitbreaker.Circuit(next)))
+	}
+}
+
+// Throttle limits calls with a token bucket of max tokens, refilled by
+// refill every d. See throttle.Throttle.
+func Throttle(max, refill uint, d time.Duration) Layer {
+	return func(next Func) Func {
+		return Func(throttle.Throttle(throttle.Effector(next), max, refill, d))
+	}
+}
+
+// Config declares a whole chain in one struct. Zero fields disable their
+// layer.
+type Config struct {
+	Timeout time.Duration
+
+	MaxRetries int
+	RetryDelay time.Duration
+
+	BreakerThreshold int
+	BreakerOptions   []circuitbreaker.Option
+
+	ThrottleMax      uint
+	ThrottleRefill   uint
+	ThrottleInterval time.Duration
+}
+
+// Layers returns the configured layers in the conventional order, outermost
+// first: timeout, retry, breaker, throttle.
+func (c Config) Layers() []Layer {
+	var layers []Layer
+
+	if c.Timeout > 0 {
+		layers = append(layers, Timeout(c.Timeout))
+	}
+	if c.MaxRetries > 0 {
+		layers = append(layers, Retry(c.MaxRetries, c.RetryDelay))
+	}
+	if c.BreakerThreshold > 0 {
+		layers = append(layers, Breaker(c.BreakerThreshold, c.BreakerOptions...))
+	}
+	if c.ThrottleMax > 0 {
+		layers = append(layers, Throttle(c.ThrottleMax, c.ThrottleRefill, c.ThrottleInterval))
+	}
+
+	return layers
+}
+
+// Wrap wraps fn with the configured layers.
+func (c Config) Wrap(fn Func) Func {
+	return Chain(fn, c.Layers()...)
+}
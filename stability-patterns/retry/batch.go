@@ -0,0 +1,127 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/1core-dev/cloud-native/concurrency-patterns/result"
+	"github.com/1core-dev/cloud-native/stability-patterns/backoff"
+)
+
+// BatchFunc processes a batch of items, such as a bulk write, and returns one
+// Result per item, in the same order. An error from the call itself fails
+// every item of the batch.
+type BatchFunc[T, R any] func(ctx context.Context, items []T) ([]result.Result[R], error)
+
+// RetryBatch returns a wrapper that calls fn with items, then retries only
+// the items that failed, until all succeed or retries run out. It waits
+// b.Delay between attempts, or backoff.Default() if b is nil.
+//
+// The wrapper returns the latest Result of every item, in the order of
+// items, and an error if some of them still failed. Items whose error is
+// Permanent, or not retryable according to WithRetryIf, are not retried.
+// WithClock, WithMaxElapsedTime, WithBudget, WithLogger, and OnRetry apply
+// as for Retry; OnRetry gets the first error of the attempt.
+func RetryBatch[T, R any](fn BatchFunc[T, R], maxRetries int, b backoff.Backoff, opts ...Option) func(context.Context, []T) ([]result.Result[R], error) {
+	o := buildOptions(opts)
+	if b == nil {
+		b = backoff.Default()
+	}
+
+	return func(ctx context.Context, items []T) ([]result.Result[R], error) {
+		start := o.clock.Now()
+
+		if o.budget != nil {
+			o.budget.deposit()
+		}
+
+		results := make([]result.Result[R], len(items))
+		pending := make([]int, len(items)) // indexes of items left to process
+		for i := range pending {
+			pending[i] = i
+		}
+
+		for r := 0; ; r++ {
+			batch := make([]T, len(pending))
+			for j, i := range pending {
+				batch[j] = items[i]
+			}
+
+			out, err := fn(context.WithValue(ctx, attemptKey{}, r+1), batch)
+			if err == nil && len(out) != len(batch) {
+				err = Permanent(fmt.Errorf("batch returned %d results for %d items", len(out), len(batch)))
+			}
+
+			// Record the outcomes, keeping only retryable failures pending
+			var (
+				retry []int
+				first error
+			)
+			for j, i := range pending {
+				res := result.Result[R]{Err: err}
+				if err == nil {
+					res = out[j]
+				}
+
+				var perm *PermanentError
+				if errors.As(res.Err, &perm) {
+					res.Err = perm.Err
+				} else if res.Err != nil && (o.retryIf == nil || o.retryIf(res.Err)) {
+					retry = append(retry, i)
+				}
+				if first == nil {
+					first = res.Err
+				}
+
+				results[i] = res
+			}
+			pending = retry
+
+			if len(pending) == 0 || r >= maxRetries {
+				return results, batchError(results)
+			}
+
+			delay := b.Delay(r)
+			if o.maxElapsed > 0 && o.clock.Since(start)+delay > o.maxElapsed {
+				return results, batchError(results) // Out of time
+			}
+			if o.budget != nil && !o.budget.withdraw() {
+				return results, fmt.Errorf("%w: %w", ErrBudgetExhausted, batchError(results))
+			}
+
+			o.log().InfoContext(ctx, "retrying batch", "attempt", r+1, "failed", len(pending), "delay", delay, "error", first)
+			for _, fn := range o.onRetry {
+				fn(r+1, first, delay)
+			}
+
+			select {
+			case <-o.clock.After(delay):
+			case <-ctx.Done():
+				return results, ctx.Err()
+			}
+		}
+	}
+}
+
+// batchError summarizes the failed items of results, or returns nil if all
+// succeeded.
+func batchError[R any](results []result.Result[R]) error {
+	var (
+		failed int
+		first  error
+	)
+	for _, res := range results {
+		if res.Err != nil {
+			if first == nil {
+				first = res.Err
+			}
+			failed++
+		}
+	}
+
+	if failed == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d items failed: %w", failed, len(results), first)
+}
@@ -0,0 +1,56 @@
+package retry
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrBudgetExhausted is joined to the last error of a call whose retries were
+// refused by its RetryBudget.
+var ErrBudgetExhausted = errors.New("retry budget exhausted")
+
+// RetryBudget caps the extra load that retries add to a dependency, shared
+// by every Retry wrapper it is given to.
+//
+// It is a token bucket of retry attempts: every call deposits ratio tokens,
+// and every retry withdraws one. With a ratio of 0.2, retries can add at most
+// 20% to the calls made, however many of them fail. The bucket starts with,
+// and never holds more than, burst tokens, so a few retries are allowed even
+// at low traffic. It is safe for concurrent use.
+type RetryBudget struct {
+	ratio float64
+	burst float64
+
+	mu      sync.Mutex
+	balance float64
+}
+
+// NewRetryBudget returns a RetryBudget in which each call earns ratio
+// retries, holding at most burst of them.
+func NewRetryBudget(ratio float64, burst int) *RetryBudget {
+	return &RetryBudget{
+		ratio:   ratio,
+		burst:   float64(burst),
+		balance: float64(burst),
+	}
+}
+
+// deposit credits the budget for one call.
+func (b *RetryBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.balance = min(b.balance+b.ratio, b.burst)
+}
+
+// withdraw takes one retry from the budget, reporting whether there was one.
+func (b *RetryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.balance < 1 {
+		return false
+	}
+	b.balance--
+	return true
+}
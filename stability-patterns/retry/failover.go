@@ -0,0 +1,42 @@
+package retry
+
+import (
+	"context"
+	"errors"
+)
+
+// RetryFailover returns a wrapper that calls primary and, if it fails, each
+// of fallbacks in turn, such as a replica and then a secondary region. It
+// returns the first success, or all the errors joined if every target fails.
+//
+// Wrap each target with Retry to exhaust its attempts before failing over:
+//
+//	call := retry.RetryFailover(
+//		retry.Retry(primary, 3, time.Second),
+//		retry.Retry(replica, 1, time.Second),
+//	)
+//
+// Failover stops early, returning ctx.Err(), once ctx is done.
+func RetryFailover[T any](primary func(context.Context) (T, error), fallbacks ...func(context.Context) (T, error)) func(context.Context) (T, error) {
+	targets := append([]func(context.Context) (T, error){primary}, fallbacks...)
+
+	return func(ctx context.Context) (T, error) {
+		var errs []error
+
+		for _, target := range targets {
+			response, err := target(ctx)
+			if err == nil {
+				return response, nil
+			}
+			errs = append(errs, err)
+
+			if ctx.Err() != nil {
+				var zero T
+				return zero, ctx.Err()
+			}
+		}
+
+		var zero T
+		return zero, errors.Join(errs...)
+	}
+}
@@ -0,0 +1,69 @@
+package retry
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Outcome describes one call made through a Retry wrapper, once it is over.
+type Outcome struct {
+	Attempts int           // attempts made, counting the first
+	Waited   time.Duration // total delay between attempts
+	Err      error         // the error returned, nil on success
+
+	// GaveUp reports that the call still failed when it stopped retrying
+	// because it ran out of retries, time, or budget, or its context was
+	// done. Errors that were not retryable at all don't count.
+	GaveUp bool
+}
+
+// Metrics receives the Outcome of every call made through a Retry wrapper,
+// for instance to export counters and alert on retry storms. Observe is
+// called synchronously before the call returns, so it must be fast.
+type Metrics interface {
+	Observe(Outcome)
+}
+
+// Counts holds the totals gathered by Stats.
+type Counts struct {
+	Calls               uint64        `json:"calls"`
+	Attempts            uint64        `json:"attempts"`
+	SucceededAfterRetry uint64        `json:"succeeded_after_retry"`
+	GaveUp              uint64        `json:"gave_up"`
+	Waited              time.Duration `json:"waited"`
+}
+
+// Stats is a Metrics that keeps running totals. Its zero value is ready to
+// use, and one Stats can be shared by many wrappers.
+type Stats struct {
+	calls     atomic.Uint64
+	attempts  atomic.Uint64
+	recovered atomic.Uint64
+	gaveUp    atomic.Uint64
+	waited    atomic.Int64
+}
+
+// Observe adds o to the totals.
+func (s *Stats) Observe(o Outcome) {
+	s.calls.Add(1)
+	s.attempts.Add(uint64(o.Attempts))
+	s.waited.Add(int64(o.Waited))
+
+	if o.Err == nil && o.Attempts > 1 {
+		s.recovered.Add(1)
+	}
+	if o.GaveUp {
+		s.gaveUp.Add(1)
+	}
+}
+
+// Counts returns the current totals.
+func (s *Stats) Counts() Counts {
+	return Counts{
+		Calls:               s.calls.Load(),
+		Attempts:            s.attempts.Load(),
+		SucceededAfterRetry: s.recovered.Load(),
+		GaveUp:              s.gaveUp.Load(),
+		Waited:              time.Duration(s.waited.Load()),
+	}
+}
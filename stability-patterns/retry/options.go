@@ -0,0 +1,127 @@
+package retry
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/1core-dev/cloud-native/stability-patterns/clock"
+)
+
+// Option configures optional retry behavior.
+type Option func(*options)
+
+type options struct {
+	clock   clock.Clock
+	retryIf func(error) bool
+	budget  *RetryBudget
+
+	retryIfResult any // func(T) bool; see WithRetryIfResult
+
+	maxElapsed     time.Duration
+	attemptTimeout time.Duration
+
+	onRetry []func(attempt int, err error, nextDelay time.Duration)
+	metrics Metrics
+	logger  *slog.Logger
+}
+
+// buildOptions applies opts over the defaults.
+func buildOptions(opts []Option) options {
+	o := options{clock: clock.Real}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithClock sets the clock that times the delay between attempts. The
+// default is clock.Real; tests can pass a *clock.Fake to skip the delays.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// WithRetryIf retries only the errors for which retryable returns true; any
+// other error is returned at once. By default every error is retried, except
+// those wrapped with Permanent.
+func WithRetryIf(retryable func(error) bool) Option {
+	return func(o *options) {
+		o.retryIf = retryable
+	}
+}
+
+// WithRetryIfResult also retries calls that succeed with a result for which
+// retry returns true, such as an empty or stale response. Once retries run
+// out, the last result is returned with ErrResultRejected. WithRetryIf does
+// not apply to these retries.
+//
+// Wrappers whose result type is not T panic when created, rather than
+// ignore the predicate.
+func WithRetryIfResult[T any](retry func(T) bool) Option {
+	return func(o *options) {
+		o.retryIfResult = retry
+	}
+}
+
+// WithBudget makes every call deposit into b and every retry withdraw from
+// it. Once b is exhausted, calls stop retrying and return their last error
+// joined with ErrBudgetExhausted.
+func WithBudget(b *RetryBudget) Option {
+	return func(o *options) {
+		o.budget = b
+	}
+}
+
+// WithMaxElapsedTime gives up once d has passed since the first attempt,
+// whatever the number of retries left. A retry whose delay would end past d
+// is not attempted; the last error is returned instead.
+func WithMaxElapsedTime(d time.Duration) Option {
+	return func(o *options) {
+		o.maxElapsed = d
+	}
+}
+
+// WithAttemptTimeout gives each attempt its own deadline of d, derived from
+// the caller's context, so one hung attempt doesn't use up the whole call.
+// An attempt that times out counts as a failed attempt and is retried. The
+// wrapper stops waiting at the deadline even if the effector ignores its
+// context; see timeout.Run.
+func WithAttemptTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.attemptTimeout = d
+	}
+}
+
+// OnRetry registers fn to be called after every failed attempt that will be
+// retried, with the attempt's number counting from 1, its error, and the
+// delay before the next one. It can be given several times; the hooks run in
+// order, after the retry is logged.
+func OnRetry(fn func(attempt int, err error, nextDelay time.Duration)) Option {
+	return func(o *options) {
+		o.onRetry = append(o.onRetry, fn)
+	}
+}
+
+// WithMetrics reports the Outcome of every call to m.
+func WithMetrics(m Metrics) Option {
+	return func(o *options) {
+		o.metrics = m
+	}
+}
+
+// WithLogger sets the logger retries are logged to. The default is
+// slog.Default(), so slog.SetDefault redirects every wrapper without one.
+func WithLogger(l *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// log returns the configured logger, or the default one.
+func (o options) log() *slog.Logger {
+	if o.logger != nil {
+		return o.logger
+	}
+	return slog.Default()
+}
@@ -4,27 +4,191 @@ package retry
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"reflect"
 	"time"
+
+	"github.com/1core-dev/cloud-native/stability-patterns/backoff"
+	"github.com/1core-dev/cloud-native/stability-patterns/timeout"
 )
 
 // Effector represents an operation that may fail transiently.
 // Retry wraps an Effector to transparently retry failed calls.
 type Effector func(context.Context) (string, error)
 
+// attemptKey is the context key for the attempt number.
+type attemptKey struct{}
+
+// AttemptFromContext returns the number of the attempt, counting from 1,
+// that ctx was passed to by Retry, or 0 if ctx did not come from Retry. The
+// wrapped effector can use it to tag logs and telemetry.
+func AttemptFromContext(ctx context.Context) int {
+	n, _ := ctx.Value(attemptKey{}).(int)
+	return n
+}
+
+// PermanentError marks an error that retrying cannot fix, such as a
+// validation error. See Permanent.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent wraps err so that Retry stops at once and returns err, unwrapped,
+// instead of retrying it. It returns nil if err is nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// ErrResultRejected is returned, with the last result, when every attempt
+// succeeded with a result rejected by WithRetryIfResult.
+var ErrResultRejected = errors.New("retry: result rejected")
+
+// RetryAfterError is implemented by errors that carry a server's hint of
+// when to try again, such as an HTTP 429 or 503 with a Retry-After header.
+// Retry waits RetryAfter() before the next attempt instead of the backoff
+// delay, unless it is not positive.
+type RetryAfterError interface {
+	error
+	RetryAfter() time.Duration
+}
+
 // Retry returns a wrapper that retries the given Effector on failure,
 // waiting delay between attempts, up to maxRetries.
 //
+// The result may be of any type, so database queries, HTTP responses, and
+// decoded structs can be retried as they are; an Effector is accepted too.
+//
+// The wait is cut short if ctx is done, in which case the wrapper returns
+// ctx.Err() without further attempts.
+//
 // Only use with idempotent operations to avoid side effects.
-func Retry(effector Effector, maxRetries int, delay time.Duration) Effector {
-	return func(ctx context.Context) (string, error) {
+func Retry[T any](effector func(context.Context) (T, error), maxRetries int, delay time.Duration, opts ...Option) func(context.Context) (T, error) {
+	return RetryWithBackoff(effector, maxRetries, backoff.Constant{Interval: delay}, opts...)
+}
+
+// RetryWithBackoff is like Retry, but waits b.Delay(r) before retry r,
+// counting from 0, so the delays can grow and be jittered. A nil b uses
+// backoff.Default().
+func RetryWithBackoff[T any](effector func(context.Context) (T, error), maxRetries int, b backoff.Backoff, opts ...Option) func(context.Context) (T, error) {
+	o := buildOptions(opts)
+	checkResultType[T](o)
+	if b == nil {
+		b = backoff.Default()
+	}
+
+	return func(ctx context.Context) (T, error) {
+		start := o.clock.Now()
+
+		if o.budget != nil {
+			o.budget.deposit()
+		}
+
+		var (
+			attempts int
+			waited   time.Duration
+		)
+
+		// done reports the outcome of the call and returns it
+		done := func(response T, err error, gaveUp bool) (T, error) {
+			if o.metrics != nil {
+				o.metrics.Observe(Outcome{
+					Attempts: attempts,
+					Waited:   waited,
+					Err:      err,
+					GaveUp:   gaveUp,
+				})
+			}
+			return response, err
+		}
+
 		for r := 0; ; r++ {
-			response, err := effector(ctx)
-			if err != nil || r >= maxRetries {
-				return response, err
+			attempts++
+			actx := context.WithValue(ctx, attemptKey{}, r+1)
+			response, err := attempt(actx, effector, o.attemptTimeout)
+			rejected := err == nil && retryResult(o, response)
+			if err == nil && !rejected {
+				return done(response, nil, false)
+			}
+			if rejected {
+				err = ErrResultRejected // Succeeded, but not with a usable result
 			}
 
-			log.Printf("Attempt %d failed; retrying in %v", r+1, delay)
+			var perm *PermanentError
+			if errors.As(err, &perm) {
+				return done(response, perm.Err, false) // Not worth retrying
+			}
+			if !rejected && o.retryIf != nil && !o.retryIf(err) {
+				return done(response, err, false)
+			}
+			if r >= maxRetries {
+				return done(response, err, true)
+			}
+
+			delay := b.Delay(r)
+			var hint RetryAfterError
+			if errors.As(err, &hint) && hint.RetryAfter() > 0 {
+				delay = hint.RetryAfter() // The server knows best
+			}
+			if o.maxElapsed > 0 && o.clock.Since(start)+delay > o.maxElapsed {
+				return done(response, err, true) // Out of time
+			}
+			if o.budget != nil && !o.budget.withdraw() {
+				return done(response, fmt.Errorf("%w: %w", ErrBudgetExhausted, err), true)
+			}
+
+			o.log().InfoContext(ctx, "retrying", "attempt", r+1, "delay", delay, "error", err)
+			for _, fn := range o.onRetry {
+				fn(r+1, err, delay)
+			}
+
+			waitStart := o.clock.Now()
+			select {
+			case <-o.clock.After(delay):
+				waited += delay
+			case <-ctx.Done():
+				waited += o.clock.Since(waitStart)
+				var zero T
+				return done(zero, ctx.Err(), true)
+			}
 		}
 	}
 }
+
+// checkResultType panics if o has a WithRetryIfResult predicate that does
+// not take results of type T.
+func checkResultType[T any](o options) {
+	if _, ok := o.retryIfResult.(func(T) bool); o.retryIfResult != nil && !ok {
+		panic(fmt.Sprintf("retry: WithRetryIfResult predicate %T for results of type %v", o.retryIfResult, reflect.TypeFor[T]()))
+	}
+}
+
+// retryResult reports whether response is rejected by the predicate of
+// WithRetryIfResult.
+func retryResult[T any](o options, response T) bool {
+	retry, ok := o.retryIfResult.(func(T) bool)
+	return ok && retry(response)
+}
+
+// attempt runs effector once, bounded by d if it is positive.
+func attempt[T any](ctx context.Context, effector func(context.Context) (T, error), d time.Duration) (T, error) {
+	if d <= 0 {
+		return effector(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+
+	return timeout.Run(ctx, effector)
+}
@@ -0,0 +1,206 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/1core-dev/cloud-native/stability-patterns/backoff"
+	"github.com/1core-dev/cloud-native/stability-patterns/clock"
+)
+
+var errFlaky = errors.New("flaky")
+
+// quiet keeps the retry log out of test output.
+var quiet = WithLogger(slog.New(slog.DiscardHandler))
+
+// drive runs fn in the background, advancing fc by step whenever fn waits on
+// it, and returns fn's result.
+func drive[T any](fc *clock.Fake, step time.Duration, fn func() (T, error)) (T, error) {
+	type outcome struct {
+		v   T
+		err error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		v, err := fn()
+		done <- outcome{v, err}
+	}()
+
+	for {
+		select {
+		case o := <-done:
+			return o.v, o.err
+		default:
+		}
+
+		if fc.Waiters() > 0 {
+			fc.Advance(step)
+		} else {
+			time.Sleep(10 * time.Microsecond)
+		}
+	}
+}
+
+// failing returns an effector that fails its first n calls, counting every
+// call in *calls.
+func failing(n int, calls *int) func(context.Context) (string, error) {
+	return func(context.Context) (string, error) {
+		*calls++
+		if *calls <= n {
+			return "", errFlaky
+		}
+		return "ok", nil
+	}
+}
+
+func TestRetryWaitsBetweenAttempts(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	stats := new(Stats)
+	var calls int
+	var delays []time.Duration
+
+	f := Retry(failing(2, &calls), 3, time.Second, quiet, WithClock(fc), WithMetrics(stats),
+		OnRetry(func(_ int, _ error, d time.Duration) { delays = append(delays, d) }))
+
+	v, err := drive(fc, time.Second, func() (string, error) { return f(context.Background()) })
+	if v != "ok" || err != nil {
+		t.Fatalf("Retry = %q, %v; want ok", v, err)
+	}
+	if calls != 3 || len(delays) != 2 {
+		t.Fatalf("%d calls and %d retries, want 3 and 2", calls, len(delays))
+	}
+	if elapsed := fc.Since(time.Unix(0, 0)); elapsed != 2*time.Second {
+		t.Errorf("waited %v in fake time, want 2s", elapsed)
+	}
+
+	want := Counts{Calls: 1, Attempts: 3, SucceededAfterRetry: 1, Waited: 2 * time.Second}
+	if got := stats.Counts(); got != want {
+		t.Errorf("Counts = %+v, want %+v", got, want)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	stats := new(Stats)
+	var calls int
+
+	f := Retry(failing(10, &calls), 2, time.Second, quiet, WithClock(fc), WithMetrics(stats))
+	if _, err := drive(fc, time.Second, func() (string, error) { return f(context.Background()) }); !errors.Is(err, errFlaky) {
+		t.Fatalf("Retry error = %v, want %v", err, errFlaky)
+	}
+	if calls != 3 {
+		t.Fatalf("%d calls, want 3", calls)
+	}
+	if c := stats.Counts(); c.GaveUp != 1 {
+		t.Errorf("GaveUp = %d, want 1", c.GaveUp)
+	}
+}
+
+func TestRetryPermanent(t *testing.T) {
+	calls := 0
+	fatal := errors.New("fatal")
+	f := Retry(func(context.Context) (string, error) {
+		calls++
+		return "", Permanent(fatal)
+	}, 3, time.Hour, quiet)
+
+	if _, err := f(context.Background()); err != fatal || calls != 1 {
+		t.Fatalf("Retry = %v after %d calls, want %v after 1", err, calls, fatal)
+	}
+}
+
+func TestRetryMaxElapsedTime(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	var calls int
+
+	f := Retry(failing(10, &calls), 10, time.Second, quiet, WithClock(fc), WithMaxElapsedTime(1500*time.Millisecond))
+	if _, err := drive(fc, time.Second, func() (string, error) { return f(context.Background()) }); !errors.Is(err, errFlaky) {
+		t.Fatalf("Retry error = %v, want %v", err, errFlaky)
+	}
+	if calls != 2 {
+		t.Fatalf("%d calls, want 2: a third would start past the limit", calls)
+	}
+}
+
+// hinted is an error asking for a retry after a given delay.
+type hinted time.Duration
+
+func (h hinted) Error() string             { return "try later" }
+func (h hinted) RetryAfter() time.Duration { return time.Duration(h) }
+
+func TestRetryAfterHint(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	calls := 0
+	var delay time.Duration
+
+	f := Retry(func(context.Context) (string, error) {
+		calls++
+		if calls == 1 {
+			return "", hinted(5 * time.Second)
+		}
+		return "ok", nil
+	}, 1, time.Second, quiet, WithClock(fc), OnRetry(func(_ int, _ error, d time.Duration) { delay = d }))
+
+	if _, err := drive(fc, 5*time.Second, func() (string, error) { return f(context.Background()) }); err != nil {
+		t.Fatalf("Retry error = %v", err)
+	}
+	if delay != 5*time.Second {
+		t.Fatalf("delay = %v, want the hinted 5s", delay)
+	}
+}
+
+func TestRetryContextDoneWhileWaiting(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls int
+
+	f := Retry(failing(10, &calls), 3, time.Hour, quiet, WithClock(fc))
+	done := make(chan error, 1)
+	go func() {
+		_, err := f(ctx)
+		done <- err
+	}()
+
+	fc.BlockUntil(1) // Waiting for the first retry
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Retry error = %v, want context.Canceled", err)
+	}
+	if calls != 1 {
+		t.Fatalf("%d calls, want 1", calls)
+	}
+}
+
+func TestRetryIfResult(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	calls := 0
+	empty := func(s string) bool { return s == "" }
+
+	f := RetryWithBackoff(func(context.Context) (string, error) {
+		calls++
+		return "", nil
+	}, 2, backoff.Constant{Interval: time.Second}, quiet, WithClock(fc), WithRetryIfResult(empty))
+
+	if _, err := drive(fc, time.Second, func() (string, error) { return f(context.Background()) }); !errors.Is(err, ErrResultRejected) {
+		t.Fatalf("Retry error = %v, want ErrResultRejected", err)
+	}
+	if calls != 3 {
+		t.Fatalf("%d calls, want 3", calls)
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	var calls int
+
+	f := Retry(failing(10, &calls), 5, time.Second, quiet, WithClock(fc), WithBudget(NewRetryBudget(0, 1)))
+	if _, err := drive(fc, time.Second, func() (string, error) { return f(context.Background()) }); !errors.Is(err, ErrBudgetExhausted) {
+		t.Fatalf("Retry error = %v, want ErrBudgetExhausted", err)
+	}
+	if calls != 2 {
+		t.Fatalf("%d calls, want 2: the budget holds one retry", calls)
+	}
+}
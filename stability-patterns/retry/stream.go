@@ -0,0 +1,123 @@
+package retry
+
+import (
+	"context"
+	"errors"
+
+	"github.com/1core-dev/cloud-native/concurrency-patterns/result"
+	"github.com/1core-dev/cloud-native/stability-patterns/backoff"
+)
+
+// OpenFunc opens a stream of values, such as a paginated fetch or a watch,
+// resuming after checkpoint; an empty checkpoint opens it from the start.
+// The stream ends when the channel is closed, and breaks when it delivers a
+// Result holding an error.
+type OpenFunc[T any] func(ctx context.Context, checkpoint string) (<-chan result.Result[T], error)
+
+// RetryStream returns a wrapper that reopens a stream whenever it fails to
+// open or breaks, resuming from the checkpoint of the last value delivered,
+// as computed by checkpoint. The values of every connection are forwarded to
+// a single channel, which is closed when the stream ends.
+//
+// The stream is reopened up to maxRetries times in a row, waiting b.Delay
+// between attempts; the count starts over whenever a value gets through. If
+// it cannot be reopened, the last error is sent and the channel closed. A
+// nil b uses backoff.Default(). WithClock, WithRetryIf, and OnRetry apply as
+// for Retry.
+func RetryStream[T any](open OpenFunc[T], checkpoint func(T) string, maxRetries int, b backoff.Backoff, opts ...Option) func(context.Context) <-chan result.Result[T] {
+	o := buildOptions(opts)
+	if b == nil {
+		b = backoff.Default()
+	}
+
+	return func(ctx context.Context) <-chan result.Result[T] {
+		out := make(chan result.Result[T])
+
+		go func() {
+			defer close(out)
+
+			var (
+				token string // checkpoint to resume from
+				r     int    // consecutive failures
+			)
+
+			for {
+				err := forward(ctx, open, token, out, func(v T) {
+					token = checkpoint(v)
+					r = 0 // Progress made
+				})
+				if err == nil || ctx.Err() != nil {
+					return // Ended, or the caller went away
+				}
+
+				var perm *PermanentError
+				if errors.As(err, &perm) {
+					err = perm.Err
+				}
+				if perm != nil || r >= maxRetries || (o.retryIf != nil && !o.retryIf(err)) {
+					select {
+					case out <- result.Fail[T](err):
+					case <-ctx.Done():
+					}
+					return
+				}
+
+				delay := b.Delay(r)
+				r++
+
+				o.log().InfoContext(ctx, "reopening stream", "attempt", r, "delay", delay, "error", err)
+				for _, fn := range o.onRetry {
+					fn(r, err, delay)
+				}
+
+				select {
+				case <-o.clock.After(delay):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+		return out
+	}
+}
+
+// forward opens the stream after token and sends its values to out, calling
+// delivered after each, until the stream ends or breaks. It returns the error
+// that broke it, or nil if it ended or ctx is done.
+func forward[T any](ctx context.Context, open OpenFunc[T], token string, out chan<- result.Result[T], delivered func(T)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // Let the producer stop if we leave early
+
+	in, err := open(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	for {
+		var (
+			res result.Result[T]
+			ok  bool
+		)
+
+		select {
+		case res, ok = <-in:
+			if !ok {
+				return nil
+			}
+		case <-ctx.Done():
+			return nil
+		}
+
+		if res.Err != nil {
+			return res.Err
+		}
+
+		select {
+		case out <- res:
+			delivered(res.Value)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
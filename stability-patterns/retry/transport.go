@@ -0,0 +1,229 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/1core-dev/cloud-native/stability-patterns/backoff"
+	"github.com/1core-dev/cloud-native/stability-patterns/clock"
+)
+
+// DefaultRetryStatuses are the response codes a Transport retries unless
+// configured otherwise: 429, 502, 503, and 504.
+//
+// They are narrower than the statuses errclass.FromHTTPStatus classes as
+// Transient or Timeout, such as 408 and 500: those say a downstream is
+// unwell, which matters to a circuit breaker, but rarely go away when the
+// same request is sent again. To retry them too, pass NewTransport its own
+// list of statuses.
+var DefaultRetryStatuses = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// Transport is an http.RoundTripper that retries idempotent requests on
+// connection errors and on retryable response codes, waiting between
+// attempts as a backoff dictates, or as long as a Retry-After header asks.
+//
+// Requests are idempotent if their method is GET, HEAD, OPTIONS, TRACE, PUT,
+// or DELETE, or if they carry an Idempotency-Key header. Other requests, and
+// requests whose body cannot be rewound with GetBody, are sent only once. If
+// every attempt gets a retryable response, the last one is returned.
+type Transport struct {
+	base           http.RoundTripper
+	maxRetries     int
+	backoff        backoff.Backoff
+	statuses       map[int]bool
+	reject         func(*http.Response) bool // from WithRetryIfResult
+	attemptTimeout time.Duration
+	clock          clock.Clock // dates in Retry-After are measured against it
+	opts           []Option
+}
+
+// NewTransport returns a Transport that sends requests through base, retrying
+// up to maxRetries times with backoff b, on connection errors and on the
+// given response codes. A nil base uses http.DefaultTransport, a nil b
+// backoff.Default(), and nil statuses DefaultRetryStatuses. The options apply
+// as for RetryWithBackoff.
+//
+// WithAttemptTimeout bounds each attempt up to its response headers, and
+// then the reading of its body, through the context of the request sent.
+// Unlike with RetryWithBackoff, no attempt is left running in the
+// background, so no response can be lost. Likewise, responses rejected by
+// a WithRetryIfResult predicate are handled like those with a retryable
+// status: closed before the next attempt, and the last one returned, with a
+// nil error, once retries run out.
+//
+//	client := &http.Client{Transport: retry.NewTransport(nil, 3, nil, nil)}
+func NewTransport(base http.RoundTripper, maxRetries int, b backoff.Backoff, statuses []int, opts ...Option) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if statuses == nil {
+		statuses = DefaultRetryStatuses
+	}
+
+	o := buildOptions(opts)
+	checkResultType[*http.Response](o)
+	reject, _ := o.retryIfResult.(func(*http.Response) bool)
+
+	t := &Transport{
+		base:           base,
+		maxRetries:     maxRetries,
+		backoff:        b,
+		statuses:       make(map[int]bool, len(statuses)),
+		reject:         reject,
+		attemptTimeout: o.attemptTimeout,
+		clock:          o.clock,
+		opts: append(slices.Clip(opts),
+			WithAttemptTimeout(0),                      // Applied by send instead; see above
+			func(o *options) { o.retryIfResult = nil }, // Likewise
+			OnRetry(discardRetried),
+		),
+	}
+	for _, code := range statuses {
+		t.statuses[code] = true
+	}
+
+	return t
+}
+
+// RoundTrip sends req, retrying it if it is safe to.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !replayable(req) {
+		return t.base.RoundTrip(req)
+	}
+
+	rewind := req.GetBody != nil && req.Body != nil && req.Body != http.NoBody
+	if rewind {
+		defer req.Body.Close() // Every attempt sends a copy from GetBody
+	}
+
+	send := func(ctx context.Context) (*http.Response, error) {
+		r, cancel := req.Clone(ctx), context.CancelFunc(func() {})
+		if t.attemptTimeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, t.attemptTimeout)
+			r = r.WithContext(ctx)
+		}
+
+		if rewind {
+			body, err := req.GetBody()
+			if err != nil {
+				cancel()
+				return nil, Permanent(err)
+			}
+			r.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(r)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+
+		rejected := t.reject != nil && t.reject(resp)
+		if rejected || t.statuses[resp.StatusCode] {
+			return nil, &statusError{resp: resp, rejected: rejected, clock: t.clock} // Closed by discardRetried
+		}
+		return resp, nil
+	}
+
+	resp, err := RetryWithBackoff(send, t.maxRetries, t.backoff, t.opts...)(req.Context())
+
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.resp, nil // Out of retries: hand over the last response
+	}
+	return resp, err
+}
+
+// discardRetried closes the response of a failed attempt about to be
+// retried.
+func discardRetried(_ int, err error, _ time.Duration) {
+	var se *statusError
+	if errors.As(err, &se) {
+		discard(se.resp)
+	}
+}
+
+// cancelBody is the body of a response whose attempt has its own timeout,
+// ending the attempt's context once the body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// replayable reports whether req can safely be sent more than once.
+func replayable(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace,
+		http.MethodPut, http.MethodDelete:
+	default:
+		if req.Header.Get("Idempotency-Key") == "" {
+			return false
+		}
+	}
+
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// discard drains and closes resp's body so its connection can be reused.
+func discard(resp *http.Response) {
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+	resp.Body.Close()
+}
+
+// statusError reports a response with a retryable status code, or one
+// rejected by the predicate of WithRetryIfResult.
+type statusError struct {
+	resp     *http.Response
+	rejected bool
+	clock    clock.Clock
+}
+
+func (e *statusError) Error() string {
+	if e.rejected {
+		return fmt.Sprintf("%v: response status %s", ErrResultRejected, e.resp.Status)
+	}
+	return fmt.Sprintf("retryable response status %s", e.resp.Status)
+}
+
+// Unwrap returns ErrResultRejected for a rejected response.
+func (e *statusError) Unwrap() error {
+	if e.rejected {
+		return ErrResultRejected
+	}
+	return nil
+}
+
+// RetryAfter returns the delay asked for by the Retry-After header, in
+// seconds or as an HTTP date measured against the Transport's clock, or 0 if
+// there is none.
+func (e *statusError) RetryAfter() time.Duration {
+	v := e.resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		return at.Sub(e.clock.Now())
+	}
+	return 0
+}
@@ -0,0 +1,129 @@
+package retry
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/1core-dev/cloud-native/stability-patterns/backoff"
+	"github.com/1core-dev/cloud-native/stability-patterns/clock"
+)
+
+// trackedBody is a response body that records whether it was closed.
+type trackedBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *trackedBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+// scripted is a RoundTripper answering with the given statuses in turn.
+type scripted struct {
+	statuses []int
+	bodies   []*trackedBody
+}
+
+func (s *scripted) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	code := s.statuses[min(len(s.bodies), len(s.statuses)-1)]
+	body := &trackedBody{Reader: strings.NewReader("body")}
+	s.bodies = append(s.bodies, body)
+
+	return &http.Response{StatusCode: code, Status: http.StatusText(code), Body: body, Header: http.Header{}}, nil
+}
+
+func TestTransportRetriesStatuses(t *testing.T) {
+	base := &scripted{statuses: []int{503, 503, 200}}
+	tr := NewTransport(base, 3, backoff.Constant{}, nil)
+
+	orig := &trackedBody{Reader: strings.NewReader("payload")}
+	req, _ := http.NewRequest(http.MethodPut, "http://example.com", strings.NewReader("payload"))
+	req.Body = orig
+
+	resp, err := tr.RoundTrip(req)
+	if err != nil || resp.StatusCode != 200 {
+		t.Fatalf("RoundTrip = %v, %v; want 200", resp, err)
+	}
+	if len(base.bodies) != 3 {
+		t.Fatalf("%d attempts, want 3", len(base.bodies))
+	}
+	for i, b := range base.bodies[:2] {
+		if !b.closed {
+			t.Errorf("response %d of a retried attempt left open", i)
+		}
+	}
+	if !orig.closed {
+		t.Error("original request body left open")
+	}
+}
+
+func TestTransportRetryAfterDate(t *testing.T) {
+	fc := clock.NewFake(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+	base := &scripted{statuses: []int{503, 200}}
+	var delay time.Duration
+	tr := NewTransport(dated{base, fc.Now().Add(5 * time.Second)}, 1, backoff.Constant{}, nil,
+		WithClock(fc), quiet, OnRetry(func(_ int, _ error, d time.Duration) { delay = d }))
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	resp, err := drive(fc, time.Second, func() (*http.Response, error) { return tr.RoundTrip(req) })
+	if err != nil || resp.StatusCode != 200 {
+		t.Fatalf("RoundTrip = %v, %v; want 200", resp, err)
+	}
+	if delay != 5*time.Second {
+		t.Fatalf("waited %v, want the 5s to the Retry-After date on the fake clock", delay)
+	}
+}
+
+// dated adds a Retry-After date to the responses of a RoundTripper.
+type dated struct {
+	http.RoundTripper
+	at time.Time
+}
+
+func (d dated) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := d.RoundTripper.RoundTrip(req)
+	if err == nil {
+		resp.Header.Set("Retry-After", d.at.Format(http.TimeFormat))
+	}
+	return resp, err
+}
+
+func TestTransportRejectedResults(t *testing.T) {
+	base := &scripted{statuses: []int{200}}
+	empty := func(resp *http.Response) bool { return resp.Header.Get("X-Ready") == "" }
+	tr := NewTransport(base, 2, backoff.Constant{}, nil, WithRetryIfResult(empty))
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	resp, err := tr.RoundTrip(req)
+	if err != nil || resp == nil {
+		t.Fatalf("RoundTrip = %v, %v; want the last response and no error", resp, err)
+	}
+	if len(base.bodies) != 3 {
+		t.Fatalf("%d attempts, want 3", len(base.bodies))
+	}
+	for i, b := range base.bodies[:2] {
+		if !b.closed {
+			t.Errorf("rejected response %d left open", i)
+		}
+	}
+	if base.bodies[2].closed {
+		t.Error("returned response closed")
+	}
+}
+
+func TestTransportRejectsMismatchedPredicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewTransport accepted a predicate for strings")
+		}
+	}()
+	NewTransport(nil, 1, nil, nil, WithRetryIfResult(func(string) bool { return true }))
+}
@@ -0,0 +1,142 @@
+// Package spike detects sudden changes in a metric by watching its rate of
+// change, so a service can react automatically — throttle harder, shed load,
+// or roll back a canary — when a counter or gauge starts moving too fast.
+//
+// A Detector fires once when the rate climbs above a high threshold and
+// again when it falls back below a lower one. The gap between the two
+// thresholds (hysteresis) keeps a rate hovering around a single threshold
+// from firing on every sample.
+package spike
+
+import (
+	"sync"
+	"time"
+
+	"github.com/1core-dev/cloud-native/stability-patterns/clock"
+)
+
+// Option configures optional Detector behavior.
+type Option func(*options)
+
+type options struct {
+	clock     clock.Clock
+	alpha     float64
+	onSpike   func(rate float64)
+	onRecover func(rate float64)
+}
+
+// WithClock sets the clock that timestamps samples. The default is
+// clock.Real.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// WithSmoothing averages the rate over samples with an exponentially
+// weighted moving average, where alpha in (0, 1] is the weight of the newest
+// sample. Lower values ignore brief blips; the default of 1 uses the raw rate.
+func WithSmoothing(alpha float64) Option {
+	return func(o *options) {
+		o.alpha = alpha
+	}
+}
+
+// OnSpike registers fn to be called with the rate when it rises above the
+// high threshold.
+func OnSpike(fn func(rate float64)) Option {
+	return func(o *options) {
+		o.onSpike = fn
+	}
+}
+
+// OnRecover registers fn to be called with the rate when, after a spike, it
+// falls below the low threshold.
+func OnRecover(fn func(rate float64)) Option {
+	return func(o *options) {
+		o.onRecover = fn
+	}
+}
+
+// Detector watches a stream of samples of a counter or gauge. It is safe for
+// concurrent use.
+type Detector struct {
+	high, low float64
+	opts      options
+
+	mu       sync.Mutex
+	last     float64   // previous sample
+	lastTime time.Time // when it was observed; zero before the first
+	rate     float64   // smoothed rate of change, per second
+	spiking  bool
+}
+
+// New returns a Detector that fires when the rate of change exceeds high
+// units per second, and recovers when it drops below low. low should be less
+// than high.
+func New(high, low float64, opts ...Option) *Detector {
+	o := options{clock: clock.Real, alpha: 1}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.alpha <= 0 || o.alpha > 1 {
+		o.alpha = 1
+	}
+
+	return &Detector{high: high, low: low, opts: o}
+}
+
+// Observe records a sample of the metric, and fires OnSpike or OnRecover if
+// the rate of change since the previous sample crosses a threshold. The
+// callbacks run synchronously, outside the Detector's lock.
+func (d *Detector) Observe(value float64) {
+	now := d.opts.clock.Now()
+
+	d.mu.Lock()
+
+	prev, prevTime := d.last, d.lastTime
+	d.last, d.lastTime = value, now
+
+	dt := now.Sub(prevTime).Seconds()
+	if prevTime.IsZero() || dt <= 0 {
+		d.mu.Unlock()
+		return // No rate yet
+	}
+
+	rate := (value - prev) / dt
+	d.rate = d.opts.alpha*rate + (1-d.opts.alpha)*d.rate
+	rate = d.rate
+
+	var fire func(float64)
+	switch {
+	case !d.spiking && rate > d.high:
+		d.spiking = true
+		fire = d.opts.onSpike
+	case d.spiking && rate < d.low:
+		d.spiking = false
+		fire = d.opts.onRecover
+	}
+
+	d.mu.Unlock()
+
+	if fire != nil {
+		fire(rate)
+	}
+}
+
+// Rate returns the current smoothed rate of change, per second.
+func (d *Detector) Rate() float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.rate
+}
+
+// Spiking reports whether the detector has fired OnSpike and not yet
+// recovered.
+func (d *Detector) Spiking() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.spiking
+}
@@ -0,0 +1,102 @@
+// Package stabilitytest provides failure-injection hooks for testing code
+// built on the stability patterns, so applications can assert how they
+// degrade without provoking real failures or sleeping through real delays.
+//
+// A Harness owns a fake clock and a set of switches. Wrappers created with
+// the Harness's options read the time from that clock and consult the
+// switches, and the test drives both:
+//
+//	h := stabilitytest.New()
+//	cb := circuitbreaker.New(3, h.BreakerOptions()...)
+//	limited := throttle.Throttle(call, 10, 1, time.Second, h.ThrottleOptions()...)
+//
+//	h.OpenBreaker(cb)        // the dependency is down
+//	h.DrainTokens()          // the rate limit is exhausted
+//	h.Advance(time.Minute)   // backoffs, windows, and refills elapse
+package stabilitytest
+
+import (
+	"sync/atomic"
+	"time"
+
+	circuitbreaker "github.com/1core-dev/cloud-native/stability-patterns/circuit-breaker"
+	"github.com/1core-dev/cloud-native/stability-patterns/clock"
+	"github.com/1core-dev/cloud-native/stability-patterns/debounce"
+	"github.com/1core-dev/cloud-native/stability-patterns/retry"
+	"github.com/1core-dev/cloud-native/stability-patterns/throttle"
+)
+
+// Epoch is the time a Harness clock starts at.
+var Epoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Harness injects failures and controls time for the wrappers created with
+// its options. It is safe for concurrent use.
+type Harness struct {
+	// Clock is the fake clock shared by every wrapper. Tests can use it
+	// directly, for instance to BlockUntil a retry is waiting.
+	Clock *clock.Fake
+
+	drained atomic.Bool
+}
+
+// New returns a Harness whose clock is set to Epoch.
+func New() *Harness {
+	return &Harness{Clock: clock.NewFake(Epoch)}
+}
+
+// BreakerOptions returns the options that put a circuit breaker under the
+// Harness's control.
+func (h *Harness) BreakerOptions() []circuitbreaker.Option {
+	return []circuitbreaker.Option{circuitbreaker.WithClock(h.Clock)}
+}
+
+// ThrottleOptions returns the options that put a throttle under the
+// Harness's control.
+func (h *Harness) ThrottleOptions() []throttle.Option {
+	return []throttle.Option{
+		throttle.WithClock(h.Clock),
+		throttle.WithDrain(h.drained.Load),
+	}
+}
+
+// DebounceOptions returns the options that put a debounced function under
+// the Harness's control.
+func (h *Harness) DebounceOptions() []debounce.Option {
+	return []debounce.Option{debounce.WithClock(h.Clock)}
+}
+
+// RetryOptions returns the options that put a retrying function under the
+// Harness's control.
+func (h *Harness) RetryOptions() []retry.Option {
+	return []retry.Option{retry.WithClock(h.Clock)}
+}
+
+// OpenBreaker forces cb open until CloseBreaker is called, as if its
+// dependency had gone down.
+func (h *Harness) OpenBreaker(cb *circuitbreaker.CircuitBreaker) {
+	cb.Trip()
+}
+
+// CloseBreaker releases a breaker forced open and clears its failures.
+func (h *Harness) CloseBreaker(cb *circuitbreaker.CircuitBreaker) {
+	cb.Reset()
+}
+
+// DrainTokens empties every throttle of the Harness and keeps them empty
+// until RefillTokens is called.
+func (h *Harness) DrainTokens() {
+	h.drained.Store(true)
+}
+
+// RefillTokens lets drained throttles refill again at their normal rate.
+// Advance the clock to make the tokens available.
+func (h *Harness) RefillTokens() {
+	h.drained.Store(false)
+}
+
+// Advance moves the clock forward by d. Breaker backoffs end, debounce
+// windows expire, throttle tokens refill, and retry delays elapse as if d
+// had really passed.
+func (h *Harness) Advance(d time.Duration) {
+	h.Clock.Advance(d)
+}
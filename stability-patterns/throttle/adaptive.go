@@ -0,0 +1,179 @@
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// AdaptiveConfig configures an Adaptive limiter. Rates are in calls per
+// second.
+type AdaptiveConfig struct {
+	MinRate float64 // the rate is never lowered below it
+	MaxRate float64 // the rate is never raised above it; also the starting rate
+	Burst   uint    // calls admitted at once above the rate; at least 1
+
+	// Increase is added to the rate after each healthy window. It defaults
+	// to a tenth of MaxRate.
+	Increase float64
+
+	// Decrease multiplies the rate after each unhealthy window. It defaults
+	// to 0.5.
+	Decrease float64
+
+	// Window is how often the rate is adjusted, from the calls completed in
+	// the last window. It defaults to one second.
+	Window time.Duration
+
+	// A window is unhealthy if the share of failed calls exceeds
+	// MaxErrorRate, which defaults to 0.1, or if MaxLatency is set and the
+	// calls' mean latency exceeds it.
+	MaxErrorRate float64
+	MaxLatency   time.Duration
+
+	// IsFailure reports whether a call's error counts as a failure. By
+	// default every error does; errclass.IsFailure leaves out the errors
+	// that say nothing about the downstream's health.
+	IsFailure func(error) bool
+}
+
+// Adaptive is a rate limiter whose rate follows the health of the downstream
+// it protects, by additive increase and multiplicative decrease (AIMD):
+// every window in which wrapped calls fail too often or run too slowly cuts
+// the rate by a factor, and every healthy one raises it by a step, so the
+// limit settles near what the downstream can take instead of a static guess.
+//
+// Calls are admitted as by a GCRA at the current rate. Outcomes are recorded
+// by Wrap, or by Record for calls admitted with Allow. The rate is adjusted
+// lazily, as outcomes are recorded, without a background goroutine. It is
+// safe for concurrent use.
+type Adaptive struct {
+	cfg   AdaptiveConfig
+	opts  options
+	limit *GCRA
+
+	mu       sync.Mutex
+	rate     float64
+	start    time.Time // when the current window began
+	calls    int
+	failures int
+	latency  time.Duration // total latency of the window's calls
+}
+
+// NewAdaptive returns an Adaptive limiter starting at cfg.MaxRate.
+func NewAdaptive(cfg AdaptiveConfig, opts ...Option) *Adaptive {
+	if cfg.Increase <= 0 {
+		cfg.Increase = cfg.MaxRate / 10
+	}
+	if cfg.Decrease <= 0 || cfg.Decrease >= 1 {
+		cfg.Decrease = 0.5
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = time.Second
+	}
+	if cfg.MaxErrorRate <= 0 {
+		cfg.MaxErrorRate = 0.1
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = func(err error) bool { return err != nil }
+	}
+
+	o := buildOptions(opts)
+	return &Adaptive{
+		cfg:   cfg,
+		opts:  o,
+		limit: NewGCRA(rateInterval(cfg.MaxRate), cfg.Burst, opts...),
+		rate:  cfg.MaxRate,
+		start: o.clock.Now(),
+	}
+}
+
+// Allow admits one call if the current rate allows it. It makes Adaptive a
+// Limiter; callers using it directly report the call's outcome with Record.
+func (l *Adaptive) Allow() bool {
+	return l.limit.Allow()
+}
+
+// Record reports the outcome of an admitted call: how long it took and the
+// error it returned.
+func (l *Adaptive) Record(latency time.Duration, err error) {
+	now := l.opts.clock.Now()
+	failed := l.cfg.IsFailure(err)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.adjust(now)
+
+	l.calls++
+	l.latency += latency
+	if failed {
+		l.failures++
+	}
+}
+
+// Rate returns the current rate, in calls per second.
+func (l *Adaptive) Rate() float64 {
+	now := l.opts.clock.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.adjust(now)
+	return l.rate
+}
+
+// Wrap returns an Effector that calls effector if the current rate allows
+// it, and records the outcome. Calls the rate does not allow are rejected
+// with ErrTooManyCalls.
+func (l *Adaptive) Wrap(effector Effector) Effector {
+	return func(ctx context.Context) (string, error) {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+
+		if !l.Allow() {
+			return "", ErrTooManyCalls
+		}
+
+		start := l.opts.clock.Now()
+		res, err := effector(ctx)
+		l.Record(l.opts.clock.Since(start), err)
+
+		return res, err
+	}
+}
+
+// adjust closes the current window once it has elapsed, moving the rate
+// according to its health and starting a new one. A window without calls
+// leaves the rate alone. Callers must hold l.mu.
+func (l *Adaptive) adjust(now time.Time) {
+	if now.Sub(l.start) < l.cfg.Window {
+		return
+	}
+
+	if l.calls > 0 {
+		unhealthy := float64(l.failures)/float64(l.calls) > l.cfg.MaxErrorRate ||
+			l.cfg.MaxLatency > 0 && l.latency/time.Duration(l.calls) > l.cfg.MaxLatency
+
+		if unhealthy {
+			l.rate = max(l.rate*l.cfg.Decrease, l.cfg.MinRate)
+		} else {
+			l.rate = min(l.rate+l.cfg.Increase, l.cfg.MaxRate)
+		}
+		l.limit.setInterval(rateInterval(l.rate))
+	}
+
+	l.start = now
+	l.calls, l.failures, l.latency = 0, 0, 0
+}
+
+// rateInterval returns the interval between calls at rate calls per second.
+// A rate of zero or less is taken as one call a day, which in practice
+// admits nothing beyond the burst.
+func rateInterval(rate float64) time.Duration {
+	if rate <= 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(float64(time.Second) / rate)
+}
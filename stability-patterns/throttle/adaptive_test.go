@@ -0,0 +1,79 @@
+package throttle
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/1core-dev/cloud-native/stability-patterns/clock"
+)
+
+func TestAdaptiveAIMD(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	l := NewAdaptive(AdaptiveConfig{MinRate: 1, MaxRate: 100, Increase: 10, Burst: 1}, WithClock(fc))
+
+	for range 10 {
+		l.Record(0, errors.New("boom"))
+	}
+	fc.Advance(time.Second)
+	if got := l.Rate(); got != 50 {
+		t.Fatalf("rate after an unhealthy window = %v, want 50", got)
+	}
+
+	l.Record(0, nil)
+	fc.Advance(time.Second)
+	if got := l.Rate(); got != 60 {
+		t.Fatalf("rate after a healthy window = %v, want 60", got)
+	}
+
+	fc.Advance(time.Second)
+	if got := l.Rate(); got != 60 {
+		t.Fatalf("rate after an empty window = %v, want 60", got)
+	}
+}
+
+// TestAdaptiveConcurrent admits calls while the rate changes; run it with
+// -race. It uses the real clock, since the locking of a Fake would order the
+// goroutines' accesses and hide races.
+func TestAdaptiveConcurrent(t *testing.T) {
+	const window = 100 * time.Microsecond
+
+	l := NewAdaptive(AdaptiveConfig{MinRate: 1, MaxRate: 1000, Burst: 10, Window: window})
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					l.Allow()
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+
+	// Alternate unhealthy and healthy windows, so every window moves the rate
+	for i := range 100 {
+		var err error
+		if i%2 == 0 {
+			err = errors.New("boom")
+		}
+		l.Record(0, err)
+		time.Sleep(window)
+		l.Rate()
+	}
+	close(done)
+	wg.Wait()
+
+	if rate := l.Rate(); rate < 1 || rate > 1000 {
+		t.Fatalf("rate %v out of bounds", rate)
+	}
+}
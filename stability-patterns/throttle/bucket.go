@@ -0,0 +1,78 @@
+package throttle
+
+import "time"
+
+// Limit configures a token bucket: it holds up to Max tokens, and Refill
+// tokens are added back every Interval.
+type Limit struct {
+	Max      uint
+	Refill   uint
+	Interval time.Duration
+}
+
+// bucket is a token bucket that is refilled lazily from the time elapsed
+// since the last refill, so it needs no background goroutine.
+type bucket struct {
+	limit  Limit
+	tokens uint      // current token count
+	debt   uint      // tokens reserved ahead of refills; only when tokens is 0
+	last   time.Time // when the last refill was applied
+}
+
+// newBucket returns a full bucket for the given limit.
+func newBucket(l Limit, now time.Time) *bucket {
+	return &bucket{limit: l, tokens: l.Max, last: now}
+}
+
+// advance adds the refills that accrued between the last refill and now,
+// paying off any debt first.
+func (b *bucket) advance(now time.Time) {
+	if b.limit.Interval <= 0 {
+		return
+	}
+
+	n := now.Sub(b.last) / b.limit.Interval // Whole intervals elapsed
+	if n <= 0 {
+		return
+	}
+
+	if b.limit.Refill > 0 && uint(n) >= (b.limit.Max-b.tokens+b.debt)/b.limit.Refill+1 {
+		b.tokens, b.debt = b.limit.Max, 0 // Avoid overflow: enough refills to fill up
+	} else {
+		added := uint(n) * b.limit.Refill
+		paid := min(added, b.debt)
+		b.debt -= paid
+		b.tokens = min(b.tokens+added-paid, b.limit.Max)
+	}
+
+	b.last = b.last.Add(n * b.limit.Interval)
+}
+
+// untilRefill returns how long after now the next refill is due, or 0 if
+// the bucket is never refilled.
+func (b *bucket) untilRefill(now time.Time) time.Duration {
+	if b.limit.Interval <= 0 || b.limit.Refill == 0 {
+		return 0
+	}
+	return max(b.last.Add(b.limit.Interval).Sub(now), 0)
+}
+
+// untilFull returns how long after now the bucket is full again, or 0 if it
+// is full or never refilled.
+func (b *bucket) untilFull(now time.Time) time.Duration {
+	if b.tokens >= b.limit.Max {
+		return 0
+	}
+	return b.untilAdded(now, b.limit.Max-b.tokens+b.debt)
+}
+
+// untilAdded returns how long after now n more tokens will have been added
+// to the bucket, or 0 if it is never refilled.
+func (b *bucket) untilAdded(now time.Time, n uint) time.Duration {
+	if n == 0 || b.limit.Interval <= 0 || b.limit.Refill == 0 {
+		return 0
+	}
+
+	refills := (n + b.limit.Refill - 1) / b.limit.Refill
+	return b.untilRefill(now) + time.Duration(refills-1)*b.limit.Interval
+}
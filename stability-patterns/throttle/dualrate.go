@@ -0,0 +1,216 @@
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Color classifies a call against a DualRate limiter, like the markers of a
+// two-rate three-color meter (RFC 2698).
+type Color int
+
+const (
+	Green  Color = iota // within the committed rate
+	Yellow              // above the committed rate but within the peak rate
+	Red                 // above the peak rate; rejected
+)
+
+// String returns the color's name.
+func (c Color) String() string {
+	switch c {
+	case Green:
+		return "green"
+	case Yellow:
+		return "yellow"
+	case Red:
+		return "red"
+	default:
+		return "unknown"
+	}
+}
+
+// colorKey is the context key for the Color of an admitted call.
+type colorKey struct{}
+
+// ColorFromContext returns the Color a DualRate limiter gave the call that
+// ctx was passed to, and Green if there was none. An effector can use it to
+// degrade Yellow calls, for instance by skipping optional work.
+func ColorFromContext(ctx context.Context) Color {
+	c, _ := ctx.Value(colorKey{}).(Color)
+	return c
+}
+
+// DualRate is a two-rate limiter. The committed limit sets the sustained
+// rate, with a small bucket; the peak limit caps the absolute rate, with a
+// larger bucket. Calls within the committed rate are Green, bursts above it
+// are Yellow and still admitted while the peak bucket lasts, and calls above
+// the peak rate are Red and rejected.
+//
+// Each key gets its own pair of buckets. A pair is evicted once it has been
+// unused for as long as both buckets take to refill, lazily by later calls
+// or by Sweep and Run, as for a KeyedThrottle. It is safe for concurrent use.
+type DualRate struct {
+	committed, peak Limit
+	idle            time.Duration
+	opts            options
+
+	mu      sync.Mutex
+	buckets map[string]*dualBucket
+	swept   time.Time // when idle buckets were last evicted
+}
+
+// dualBucket is the pair of buckets of one key and when it was last used.
+type dualBucket struct {
+	committed, peak *bucket
+	used            time.Time
+}
+
+// NewDualRate returns a DualRate limiter with the given committed and peak
+// limits. If either never refills, buckets are never evicted.
+func NewDualRate(committed, peak Limit, opts ...Option) *DualRate {
+	var idle time.Duration
+	if c, p := refillIdle(committed, 0), refillIdle(peak, 0); c > 0 && p > 0 {
+		idle = max(c, p)
+	}
+
+	d := &DualRate{
+		committed: committed,
+		peak:      peak,
+		idle:      idle,
+		opts:      buildOptions(opts),
+		buckets:   make(map[string]*dualBucket),
+	}
+	d.swept = d.opts.clock.Now()
+
+	return d
+}
+
+// Mark classifies a call for key and takes its tokens: from both buckets if
+// Green, from the peak bucket only if Yellow, and none if Red.
+func (d *DualRate) Mark(key string) Color {
+	now := d.opts.clock.Now()
+
+	d.mu.Lock()
+
+	d.sweep(now, false)
+
+	b, ok := d.buckets[key]
+	if !ok {
+		b = &dualBucket{committed: newBucket(d.committed, now), peak: newBucket(d.peak, now)}
+		d.buckets[key] = b
+	}
+	b.used = now
+
+	b.committed.advance(now)
+	b.peak.advance(now)
+	d.opts.drain(b.peak)
+
+	var c Color
+	switch {
+	case b.peak.tokens == 0:
+		c = Red
+	case b.committed.tokens == 0:
+		b.peak.tokens--
+		c = Yellow
+	default:
+		b.peak.tokens--
+		b.committed.tokens--
+		c = Green
+	}
+
+	left := b.peak.tokens
+
+	d.mu.Unlock()
+
+	d.opts.observe(c != Red, left, 0)
+	return c
+}
+
+// Len returns the number of keys that have buckets, including idle ones not
+// evicted yet.
+func (d *DualRate) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return len(d.buckets)
+}
+
+// Sweep evicts the buckets of every key that has been idle for the idle
+// period, and returns how many it evicted.
+func (d *DualRate) Sweep() int {
+	now := d.opts.clock.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.sweep(now, true)
+}
+
+// Run calls Sweep every idle period until ctx is done. It returns at once
+// if buckets are never evicted.
+func (d *DualRate) Run(ctx context.Context) {
+	if d.idle <= 0 {
+		return
+	}
+
+	ticker := d.opts.clock.NewTicker(d.idle)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			d.Sweep()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sweep evicts the buckets unused for the idle period, at most once per
+// period unless forced, and returns how many it evicted. Callers must hold
+// d.mu.
+func (d *DualRate) sweep(now time.Time, force bool) int {
+	if d.idle <= 0 || !force && now.Sub(d.swept) < d.idle {
+		return 0
+	}
+
+	n := 0
+	for key, b := range d.buckets {
+		if now.Sub(b.used) >= d.idle {
+			delete(d.buckets, key)
+			n++
+		}
+	}
+	d.swept = now
+	return n
+}
+
+// Wrap returns an Effector that marks every call with the key from key, a
+// nil key sharing one pair of buckets. Red calls are rejected with
+// ErrTooManyCalls; the others run with their Color in the context.
+func (d *DualRate) Wrap(effector Effector, key KeyFunc) Effector {
+	return func(ctx context.Context) (string, error) {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+
+		var k string
+		if key != nil {
+			k = key(ctx)
+		}
+
+		c := d.Mark(k)
+		if c == Red {
+			return "", ErrTooManyCalls
+		}
+
+		// Green is what ColorFromContext reports by default, so most calls
+		// need no context allocation
+		if c != ColorFromContext(ctx) {
+			ctx = context.WithValue(ctx, colorKey{}, c)
+		}
+
+		return effector(ctx)
+	}
+}
@@ -0,0 +1,69 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/1core-dev/cloud-native/stability-patterns/clock"
+)
+
+func TestDualRateColors(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	d := NewDualRate(Limit{Max: 1, Refill: 1, Interval: time.Second},
+		Limit{Max: 3, Refill: 3, Interval: time.Second}, WithClock(fc))
+
+	var got []Color
+	for range 4 {
+		got = append(got, d.Mark("k"))
+	}
+	want := []Color{Green, Yellow, Yellow, Red}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("colors = %v, want %v", got, want)
+		}
+	}
+
+	fc.Advance(time.Second)
+	if c := d.Mark("k"); c != Green {
+		t.Fatalf("color after a refill = %v, want green", c)
+	}
+}
+
+func TestDualRateWrap(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	d := NewDualRate(Limit{Max: 1, Refill: 1, Interval: time.Second},
+		Limit{Max: 2, Refill: 2, Interval: time.Second}, WithClock(fc))
+
+	f := d.Wrap(func(ctx context.Context) (string, error) {
+		return ColorFromContext(ctx).String(), nil
+	}, nil)
+
+	for _, want := range []string{"green", "yellow"} {
+		if got, err := f(context.Background()); got != want || err != nil {
+			t.Fatalf("call = %q, %v; want %q", got, err, want)
+		}
+	}
+	if _, err := f(context.Background()); !errors.Is(err, ErrTooManyCalls) {
+		t.Fatalf("red call error = %v, want ErrTooManyCalls", err)
+	}
+}
+
+func TestDualRateEviction(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	d := NewDualRate(Limit{Max: 1, Refill: 1, Interval: time.Second},
+		Limit{Max: 4, Refill: 2, Interval: time.Second}, WithClock(fc))
+
+	d.Mark("a")
+	d.Mark("b")
+	if n := d.Sweep(); n != 0 {
+		t.Fatalf("Sweep evicted %d fresh keys", n)
+	}
+
+	fc.Advance(2 * time.Second) // Both buckets refill from empty in 2s
+	d.Mark("c")                 // Sweeps lazily
+	if n := d.Len(); n != 1 {
+		t.Fatalf("Len = %d after the idle period, want 1", n)
+	}
+}
@@ -0,0 +1,139 @@
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// GCRA is a rate limiter implementing the Generic Cell Rate Algorithm. It
+// admits calls at a sustained rate of one per interval, and tolerates bursts
+// of up to burst calls above that rate, the two being set independently.
+//
+// Its whole state is a single time, the theoretical arrival time (TAT): the
+// moment at which the limiter would be idle again if it admitted no more
+// calls. A call is admitted if moving the TAT forward by its cost keeps it
+// within burst intervals of now. Since the TAT is exact, so is the time a
+// rejected caller should wait; see RetryAfter.
+//
+// It is safe for concurrent use.
+type GCRA struct {
+	interval time.Duration
+	burst    uint
+	opts     options
+
+	mu  sync.Mutex
+	tat time.Time
+}
+
+// NewGCRA returns a GCRA admitting one call every interval on average, and
+// up to burst calls at once. A burst of zero is treated as one.
+func NewGCRA(interval time.Duration, burst uint, opts ...Option) *GCRA {
+	return &GCRA{interval: interval, burst: max(burst, 1), opts: buildOptions(opts)}
+}
+
+// Allow admits one call if the rate allows it. It makes GCRA a Limiter.
+func (l *GCRA) Allow() bool {
+	return l.AllowN(context.Background(), 1)
+}
+
+// AllowN admits a call costing n calls' worth of rate if the rate allows it,
+// and reports whether it did. A call costing more than the burst is never
+// admitted.
+func (l *GCRA) AllowN(_ context.Context, n uint) bool {
+	now := l.opts.clock.Now()
+
+	l.mu.Lock()
+
+	tat := l.theoretical(now)
+	next := tat.Add(time.Duration(n) * l.interval)
+	ok := n <= l.burst && !next.After(now.Add(l.tolerance()))
+	if ok {
+		l.tat = next
+		tat = next
+	}
+	left := l.remaining(tat, now)
+
+	l.mu.Unlock()
+
+	l.opts.observe(ok, left, 0)
+	return ok
+}
+
+// TAT returns the theoretical arrival time: once it has passed, the limiter
+// admits a full burst again.
+func (l *GCRA) TAT() time.Time {
+	now := l.opts.clock.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.theoretical(now)
+}
+
+// RetryAfter returns how long a call costing n must wait before AllowN would
+// admit it, or zero if it would be admitted now. It is exact as long as no
+// other call is admitted in between, which makes it suited to a Retry-After
+// header.
+func (l *GCRA) RetryAfter(n uint) time.Duration {
+	now := l.opts.clock.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	next := l.theoretical(now).Add(time.Duration(n) * l.interval)
+	return max(next.Sub(now.Add(l.tolerance())), 0)
+}
+
+// Wrap returns an Effector that admits calls at the cost declared in their
+// context, one by default, before calling effector. Calls the rate does not
+// allow are rejected with ErrTooManyCalls.
+func (l *GCRA) Wrap(effector Effector) Effector {
+	return func(ctx context.Context) (string, error) {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+
+		if !l.AllowN(ctx, CostFromContext(ctx)) {
+			return "", ErrTooManyCalls
+		}
+
+		return effector(ctx)
+	}
+}
+
+// setInterval changes the sustained rate, keeping the TAT.
+func (l *GCRA) setInterval(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.interval = d
+}
+
+// theoretical returns the TAT, no earlier than now. Callers must hold l.mu.
+func (l *GCRA) theoretical(now time.Time) time.Time {
+	if l.opts.drained != nil && l.opts.drained() {
+		l.tat = now.Add(l.tolerance()) // As if a burst had just been admitted
+	}
+
+	if l.tat.Before(now) {
+		return now
+	}
+	return l.tat
+}
+
+// tolerance is how far ahead of now the TAT may run. Callers must hold l.mu.
+func (l *GCRA) tolerance() time.Duration {
+	return time.Duration(l.burst) * l.interval
+}
+
+// remaining returns how many calls of cost one a TAT of tat leaves room for.
+// Callers must hold l.mu.
+func (l *GCRA) remaining(tat, now time.Time) uint {
+	if l.interval <= 0 {
+		return l.burst
+	}
+
+	ahead := tat.Sub(now)
+	return uint((l.tolerance() - ahead) / l.interval)
+}
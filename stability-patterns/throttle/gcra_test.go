@@ -0,0 +1,72 @@
+package throttle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/1core-dev/cloud-native/stability-patterns/clock"
+)
+
+func TestGCRABurstAndRate(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	l := NewGCRA(time.Second, 3, WithClock(fc))
+
+	for i := range 3 {
+		if !l.Allow() {
+			t.Fatalf("call %d of the burst rejected", i)
+		}
+	}
+	if l.Allow() {
+		t.Fatal("call past the burst admitted")
+	}
+	if d := l.RetryAfter(1); d != time.Second {
+		t.Fatalf("RetryAfter = %v, want 1s", d)
+	}
+
+	fc.Advance(time.Second)
+	if !l.Allow() {
+		t.Fatal("call after one interval rejected")
+	}
+	if l.Allow() {
+		t.Fatal("second call after one interval admitted")
+	}
+}
+
+func TestGCRACost(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	l := NewGCRA(time.Second, 4, WithClock(fc))
+	ctx := context.Background()
+
+	if l.AllowN(ctx, 5) {
+		t.Fatal("call costing more than the burst admitted")
+	}
+	if !l.AllowN(ctx, 3) {
+		t.Fatal("call costing 3 of 4 rejected")
+	}
+	if d := l.RetryAfter(3); d != 2*time.Second {
+		t.Fatalf("RetryAfter(3) = %v, want 2s", d)
+	}
+
+	fc.Advance(2 * time.Second)
+	if !l.AllowN(ctx, 3) {
+		t.Fatal("call admitted by RetryAfter rejected")
+	}
+}
+
+func TestGCRARemaining(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	stats := new(Stats)
+	l := NewGCRA(time.Second, 3, WithClock(fc), WithMetrics(stats))
+
+	l.Allow()
+	if got := stats.Counts().Tokens; got != 2 {
+		t.Fatalf("remaining after one call = %d, want 2", got)
+	}
+
+	fc.Advance(time.Hour) // Idle: the TAT is in the past
+	l.Allow()
+	if got := stats.Counts().Tokens; got != 2 {
+		t.Fatalf("remaining after idling = %d, want 2", got)
+	}
+}
@@ -0,0 +1,79 @@
+package throttle
+
+import "context"
+
+// KeyFunc extracts the key (tenant, user, client IP...) that selects the
+// per-key bucket for a call.
+type KeyFunc func(context.Context) string
+
+// Hierarchical applies two token bucket limits to an Effector: an independent
+// bucket per key, and one global bucket shared by all keys.
+//
+// A call proceeds only if both its key's bucket and the global bucket have a
+// token. Tokens are taken from both or from neither, so a call rejected by one
+// bucket never drains the other. This enforces tenant fairness and aggregate
+// protection together.
+//
+// A key's bucket is evicted once it has been unused for as long as it takes
+// to refill, since it is then indistinguishable from a new one; calls sweep
+// the idle buckets at most once per such period, so the keys seen over time
+// don't grow memory without bound. With no per-key refill, buckets are never
+// evicted.
+func Hierarchical(effector Effector, key KeyFunc, global, perKey Limit, opts ...Option) Effector {
+	o := buildOptions(opts)
+	idle := refillIdle(perKey, 0)
+
+	// keys holds the per-key buckets, created on first use. Its mutex guards
+	// the shared global bucket too, so consumption is atomic.
+	var (
+		root *bucket
+		keys keyedShard
+	)
+
+	return func(ctx context.Context) (string, error) {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+
+		k := key(ctx)
+		p := PriorityFromContext(ctx)
+		now := o.clock.Now()
+
+		keys.mu.Lock()
+
+		if root == nil {
+			root = newBucket(global, now)
+			keys.buckets = make(map[string]*keyedBucket)
+			keys.swept = now
+		}
+		keys.sweep(now, idle, false)
+
+		b, ok := keys.buckets[k]
+		if !ok {
+			b = &keyedBucket{bucket: *newBucket(perKey, now)}
+			keys.buckets[k] = b
+		}
+		b.used = now
+
+		root.advance(now)
+		b.advance(now)
+		o.drain(root)
+
+		// Check both buckets before taking from either
+		if !o.admits(p, root.tokens, root.limit.Max) || !o.admits(p, b.tokens, b.limit.Max) {
+			left := root.tokens
+			keys.mu.Unlock()
+			o.observe(false, left, 0)
+			return "", ErrTooManyCalls
+		}
+
+		root.tokens--
+		b.tokens--
+		left := root.tokens
+
+		keys.mu.Unlock()
+		o.observe(true, left, 0)
+
+		return effector(ctx)
+	}
+}
@@ -0,0 +1,51 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/1core-dev/cloud-native/stability-patterns/clock"
+)
+
+// tenantKey is the context key of the tenant in these tests.
+type tenantKey struct{}
+
+func byTenant(ctx context.Context) string {
+	s, _ := ctx.Value(tenantKey{}).(string)
+	return s
+}
+
+func TestHierarchical(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	f := Hierarchical(noop, byTenant,
+		Limit{Max: 3, Refill: 3, Interval: time.Second},
+		Limit{Max: 2, Refill: 2, Interval: time.Second}, WithClock(fc))
+	call := func(tenant string) error {
+		_, err := f(context.WithValue(context.Background(), tenantKey{}, tenant))
+		return err
+	}
+
+	for _, tenant := range []string{"a", "a"} {
+		if err := call(tenant); err != nil {
+			t.Fatalf("tenant %s: %v", tenant, err)
+		}
+	}
+	if err := call("a"); !errors.Is(err, ErrTooManyCalls) {
+		t.Fatalf("third call of a: %v, want ErrTooManyCalls from its own bucket", err)
+	}
+
+	// a's rejected call took nothing from the global bucket: one token left
+	if err := call("b"); err != nil {
+		t.Fatalf("tenant b: %v", err)
+	}
+	if err := call("c"); !errors.Is(err, ErrTooManyCalls) {
+		t.Fatalf("tenant c: %v, want ErrTooManyCalls from the global bucket", err)
+	}
+
+	fc.Advance(time.Second)
+	if err := call("c"); err != nil {
+		t.Fatalf("tenant c after a refill: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package throttle
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RequestKeyFunc extracts the client key that selects the bucket of an HTTP
+// request, such as its IP address or API token.
+type RequestKeyFunc func(*http.Request) string
+
+// ByIP keys requests by the IP address of the connection's remote end.
+// Behind a proxy, key by the header carrying the client address instead.
+func ByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ByHeader keys requests by the value of the named header, such as
+// "X-API-Key" or "X-Forwarded-For".
+func ByHeader(name string) RequestKeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// ByBearerToken keys requests by the token of their "Authorization: Bearer"
+// header.
+func ByBearerToken(r *http.Request) string {
+	const prefix = "Bearer "
+
+	auth := r.Header.Get("Authorization")
+	if len(auth) < len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return ""
+	}
+	return auth[len(prefix):]
+}
+
+// Middleware returns an http.Handler that takes a token from the bucket of
+// each request's client key before calling next. Requests without a token
+// get 429 Too Many Requests, with a Retry-After header giving the seconds
+// until the next refill. Requests whose key is empty share one bucket.
+//
+// With rateLimitHeaders, every response also carries X-RateLimit-Limit,
+// X-RateLimit-Remaining, and X-RateLimit-Reset, the seconds until the
+// client's bucket is full again.
+func Middleware(k *KeyedThrottle, key RequestKeyFunc, rateLimitHeaders bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok, st := k.take(key(r), PriorityFromContext(r.Context()))
+
+		if rateLimitHeaders {
+			h := w.Header()
+			h.Set("X-RateLimit-Limit", strconv.FormatUint(uint64(k.limit.Max), 10))
+			h.Set("X-RateLimit-Remaining", strconv.FormatUint(uint64(st.remaining), 10))
+			h.Set("X-RateLimit-Reset", seconds(st.untilFull))
+		}
+
+		if !ok {
+			if st.untilRefill > 0 {
+				w.Header().Set("Retry-After", seconds(max(st.untilRefill, time.Second)))
+			}
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// seconds formats d as whole seconds, rounded up.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
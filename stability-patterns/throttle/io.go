@@ -0,0 +1,105 @@
+package throttle
+
+import (
+	"context"
+	"io"
+)
+
+// Reader returns an io.Reader that paces reads from r to the rate of l, one
+// token per byte, for bandwidth-limiting downloads, backups, or replication
+// streams. It is NewReader with a context that is never done.
+func Reader(r io.Reader, l *TokenBucket) io.Reader {
+	return NewReader(context.Background(), r, l)
+}
+
+// NewReader returns an io.Reader that paces reads from r to the rate of l,
+// one token per byte. Reads are capped at what the bucket can grant at once,
+// and each one waits for the tokens of the bytes it returned, so the stream
+// never runs ahead of the rate by more than one read.
+//
+// Waits take the priority in ctx into account, as set by WithPriority, and
+// end with ctx.Err() once ctx is done. Reads fail with ErrTooManyCalls if the
+// reserve for that priority leaves no tokens at all.
+func NewReader(ctx context.Context, r io.Reader, l *TokenBucket) io.Reader {
+	return &reader{ctx: ctx, r: r, l: l}
+}
+
+type reader struct {
+	ctx context.Context
+	r   io.Reader
+	l   *TokenBucket
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	size := r.l.chunk(r.ctx)
+	if size == 0 {
+		return 0, ErrTooManyCalls
+	}
+	p = p[:min(uint(len(p)), size)]
+
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if werr := r.l.WaitN(r.ctx, uint(n)); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// Writer returns an io.Writer that paces writes to w to the rate of l, one
+// token per byte, for bandwidth-limiting uploads or replication streams. It
+// is NewWriter with a context that is never done.
+func Writer(w io.Writer, l *TokenBucket) io.Writer {
+	return NewWriter(context.Background(), w, l)
+}
+
+// NewWriter returns an io.Writer that paces writes to w to the rate of l,
+// one token per byte. Large writes are split into chunks of at most what the
+// bucket can grant at once, each written once its tokens are taken.
+//
+// Waits take the priority in ctx into account and end with ctx.Err() once
+// ctx is done. Writes fail with ErrTooManyCalls if the reserve for that
+// priority leaves no tokens at all.
+func NewWriter(ctx context.Context, w io.Writer, l *TokenBucket) io.Writer {
+	return &writer{ctx: ctx, w: w, l: l}
+}
+
+type writer struct {
+	ctx context.Context
+	w   io.Writer
+	l   *TokenBucket
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	var written int
+
+	size := w.l.chunk(w.ctx)
+	if size == 0 && len(p) > 0 {
+		return 0, ErrTooManyCalls
+	}
+
+	for len(p) > 0 {
+		chunk := p[:min(uint(len(p)), size)]
+		if err := w.l.WaitN(w.ctx, uint(len(chunk))); err != nil {
+			return written, err
+		}
+
+		n, err := w.w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+
+	return written, nil
+}
+
+// chunk returns the largest number of bytes to move in one step: the most
+// tokens WaitN can grant at once to the priority in ctx, which is the
+// bucket's capacity less that priority's reserve. It is 0 if no call of that
+// priority can ever pass.
+func (l *TokenBucket) chunk(ctx context.Context) uint {
+	limit := l.bucket.limit.Max
+	return limit - min(l.opts.reserved(PriorityFromContext(ctx), limit), limit)
+}
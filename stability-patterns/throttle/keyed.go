@@ -0,0 +1,240 @@
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// keyedShards is the number of independently locked partitions of a
+// KeyedThrottle, so calls for different keys rarely contend.
+const keyedShards = 32
+
+// KeyedThrottle keeps an independent token bucket per key, such as a user,
+// tenant, or client IP, so one wrapper can rate-limit each of them
+// separately.
+//
+// Buckets left unused for the idle period are evicted lazily, by later calls
+// that hash to the same partition, so the number of keys seen over time
+// doesn't grow memory without bound. On a public endpoint, where most keys
+// are seen once, run Run as well to evict them from quiet partitions too.
+// It is safe for concurrent use.
+type KeyedThrottle struct {
+	limit Limit
+	idle  time.Duration
+	opts  options
+
+	shards  [keyedShards]keyedShard
+	evicted atomic.Uint64
+}
+
+// KeyedStats is a snapshot of a KeyedThrottle's keys.
+type KeyedStats struct {
+	Keys    int    `json:"keys"`    // keys with a bucket, as Len reports
+	Evicted uint64 `json:"evicted"` // buckets evicted for being idle so far
+}
+
+// keyedShard is one partition of the buckets of a KeyedThrottle.
+type keyedShard struct {
+	mu      sync.Mutex
+	buckets map[string]*keyedBucket
+	swept   time.Time // when idle buckets were last evicted
+}
+
+// keyedBucket is the bucket of one key and when it was last used.
+type keyedBucket struct {
+	bucket
+	used time.Time
+}
+
+// NewKeyedThrottle returns a KeyedThrottle applying limit to every key.
+//
+// A bucket idle for long enough to refill is indistinguishable from a new
+// one, so idle defaults to the time it takes to refill from empty, and
+// shorter periods are raised to it. With no refill, buckets are never
+// evicted.
+func NewKeyedThrottle(limit Limit, idle time.Duration, opts ...Option) *KeyedThrottle {
+	k := &KeyedThrottle{limit: limit, idle: refillIdle(limit, idle), opts: buildOptions(opts)}
+	for i := range k.shards {
+		k.shards[i].buckets = make(map[string]*keyedBucket)
+	}
+
+	return k
+}
+
+// Allow takes a token from key's bucket, creating it full on first use. It
+// reports whether one was available.
+func (k *KeyedThrottle) Allow(key string) bool {
+	ok, _ := k.take(key, Normal)
+	return ok
+}
+
+// keyedState is the state of a key's bucket after a call to take.
+type keyedState struct {
+	remaining   uint          // tokens left
+	untilRefill time.Duration // until the next token is added
+	untilFull   time.Duration // until the bucket is full again
+}
+
+// take is Allow for a call of priority p, also returning the state of the
+// bucket afterwards.
+func (k *KeyedThrottle) take(key string, p Priority) (bool, keyedState) {
+	now := k.opts.clock.Now()
+	s := k.shard(key)
+
+	s.mu.Lock()
+
+	k.evicted.Add(uint64(s.sweep(now, k.idle, false)))
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &keyedBucket{bucket: *newBucket(k.limit, now)}
+		s.buckets[key] = b
+	}
+	b.used = now
+
+	b.advance(now)
+	k.opts.drain(&b.bucket)
+
+	ok = k.opts.admits(p, b.tokens, b.limit.Max)
+	if ok {
+		b.tokens--
+	}
+	state := keyedState{
+		remaining:   b.tokens,
+		untilRefill: b.untilRefill(now),
+		untilFull:   b.untilFull(now),
+	}
+
+	s.mu.Unlock()
+
+	k.opts.observe(ok, state.remaining, 0)
+	return ok, state
+}
+
+// Len returns the number of keys that have a bucket, including idle ones not
+// evicted yet.
+func (k *KeyedThrottle) Len() int {
+	n := 0
+	for i := range k.shards {
+		s := &k.shards[i]
+		s.mu.Lock()
+		n += len(s.buckets)
+		s.mu.Unlock()
+	}
+	return n
+}
+
+// Sweep evicts the buckets of every partition that have been idle for the
+// idle period, and returns how many it evicted.
+func (k *KeyedThrottle) Sweep() int {
+	now := k.opts.clock.Now()
+
+	n := 0
+	for i := range k.shards {
+		s := &k.shards[i]
+		s.mu.Lock()
+		n += s.sweep(now, k.idle, true)
+		s.mu.Unlock()
+	}
+
+	k.evicted.Add(uint64(n))
+	return n
+}
+
+// Run calls Sweep every idle period until ctx is done. It returns at once
+// if buckets are never evicted.
+func (k *KeyedThrottle) Run(ctx context.Context) {
+	if k.idle <= 0 {
+		return
+	}
+
+	ticker := k.opts.clock.NewTicker(k.idle)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			k.Sweep()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Stats returns the number of keys and of evictions so far.
+func (k *KeyedThrottle) Stats() KeyedStats {
+	return KeyedStats{Keys: k.Len(), Evicted: k.evicted.Load()}
+}
+
+// WritePrometheus writes the current KeyedStats to w in the Prometheus text
+// exposition format, with metric names starting with prefix.
+func (k *KeyedThrottle) WritePrometheus(w io.Writer, prefix string) error {
+	st := k.Stats()
+
+	_, err := fmt.Fprintf(w, `# HELP %[1]s_keys Keys with a bucket.
+# TYPE %[1]s_keys gauge
+%[1]s_keys %[2]d
+# HELP %[1]s_evicted_total Buckets evicted for being idle.
+# TYPE %[1]s_evicted_total counter
+%[1]s_evicted_total %[3]d
+`, prefix, st.Keys, st.Evicted)
+
+	return err
+}
+
+// Wrap applies the limiter to effector, using key to select the bucket of
+// each call. Calls without a token are rejected with ErrTooManyCalls.
+func (k *KeyedThrottle) Wrap(effector Effector, key KeyFunc) Effector {
+	return func(ctx context.Context) (string, error) {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+
+		if ok, _ := k.take(key(ctx), PriorityFromContext(ctx)); !ok {
+			return "", ErrTooManyCalls
+		}
+
+		return effector(ctx)
+	}
+}
+
+// shard returns the partition holding key.
+func (k *KeyedThrottle) shard(key string) *keyedShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return &k.shards[h.Sum32()%keyedShards]
+}
+
+// refillIdle returns idle raised to the time a bucket of limit takes to
+// refill from empty, after which it can be evicted, or 0 if it never refills.
+func refillIdle(limit Limit, idle time.Duration) time.Duration {
+	if limit.Refill == 0 || limit.Interval <= 0 {
+		return 0
+	}
+
+	refills := (limit.Max + limit.Refill - 1) / limit.Refill
+	return max(idle, time.Duration(refills)*limit.Interval)
+}
+
+// sweep evicts the buckets unused for idle, at most once per idle period
+// unless forced, and returns how many it evicted. Callers must hold s.mu.
+func (s *keyedShard) sweep(now time.Time, idle time.Duration, force bool) int {
+	if idle <= 0 || !force && now.Sub(s.swept) < idle {
+		return 0
+	}
+
+	n := 0
+	for key, b := range s.buckets {
+		if now.Sub(b.used) >= idle {
+			delete(s.buckets, key)
+			n++
+		}
+	}
+	s.swept = now
+	return n
+}
@@ -0,0 +1,102 @@
+package throttle
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/1core-dev/cloud-native/stability-patterns/clock"
+)
+
+func TestKeyedThrottleKeysAreIndependent(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	k := NewKeyedThrottle(Limit{Max: 1, Refill: 1, Interval: time.Second}, 0, WithClock(fc))
+
+	if !k.Allow("a") || k.Allow("a") {
+		t.Fatal("key a: want one call, then a rejection")
+	}
+	if !k.Allow("b") {
+		t.Fatal("key b limited by key a")
+	}
+
+	fc.Advance(time.Second)
+	if !k.Allow("a") {
+		t.Fatal("key a not refilled")
+	}
+}
+
+func TestKeyedThrottleSweep(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	k := NewKeyedThrottle(Limit{Max: 2, Refill: 1, Interval: time.Second}, 0, WithClock(fc))
+
+	k.Allow("a")
+	k.Allow("b")
+	fc.Advance(time.Second)
+	k.Allow("b")
+
+	// Idle defaults to the time to refill from empty: 2s
+	fc.Advance(time.Second)
+	if n := k.Sweep(); n != 1 {
+		t.Fatalf("Sweep evicted %d, want 1: a only", n)
+	}
+	if got := k.Stats(); got != (KeyedStats{Keys: 1, Evicted: 1}) {
+		t.Fatalf("Stats = %+v, want 1 key and 1 eviction", got)
+	}
+
+	var b strings.Builder
+	if err := k.WritePrometheus(&b, "api"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "api_keys 1\n") || !strings.Contains(b.String(), "api_evicted_total 1\n") {
+		t.Fatalf("unexpected exposition:\n%s", b.String())
+	}
+}
+
+func TestKeyedThrottleRun(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	k := NewKeyedThrottle(Limit{Max: 1, Refill: 1, Interval: time.Second}, 0, WithClock(fc))
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		k.Run(ctx)
+		close(done)
+	}()
+
+	k.Allow("a")
+	fc.BlockUntil(1) // Run's ticker
+	fc.Advance(time.Second)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for k.Len() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Run did not evict the idle key")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	<-done
+}
+
+// reentrant is a Metrics hook that calls back into a KeyedThrottle.
+type reentrant struct{ k *KeyedThrottle }
+
+func (r *reentrant) Observe(Decision) { r.k.Len() }
+
+func TestKeyedThrottleObservesOutsideTheLock(t *testing.T) {
+	hook := new(reentrant)
+	hook.k = NewKeyedThrottle(Limit{Max: 1, Refill: 1, Interval: time.Second}, 0, WithMetrics(hook))
+
+	done := make(chan struct{})
+	go func() {
+		hook.k.Allow("a")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Allow deadlocked on a metrics hook calling into the throttle")
+	}
+}
@@ -0,0 +1,90 @@
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// LeakyBucket admits calls at a steady pace, one every interval, queueing
+// the calls that arrive faster. Unlike a token bucket it never lets a burst
+// through: traffic leaves it smoothed to the constant rate, which suits
+// downstreams that cannot absorb spikes.
+//
+// At most depth calls wait in the queue; further calls are rejected. It is
+// safe for concurrent use.
+type LeakyBucket struct {
+	interval time.Duration
+	depth    int
+	opts     options
+
+	mu   sync.Mutex
+	next time.Time // when the next queued call may leave
+}
+
+// NewLeakyBucket returns a LeakyBucket releasing one call every interval,
+// with up to depth calls waiting.
+func NewLeakyBucket(interval time.Duration, depth int, opts ...Option) *LeakyBucket {
+	return &LeakyBucket{interval: interval, depth: depth, opts: buildOptions(opts)}
+}
+
+// Wait blocks until the call's turn comes. It returns ErrTooManyCalls at
+// once if depth calls are already waiting, or ctx.Err() if ctx is done
+// first. A call that gives up leaves its turn unused.
+func (l *LeakyBucket) Wait(ctx context.Context) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	now := l.opts.clock.Now()
+
+	l.mu.Lock()
+
+	slot := l.next
+	if slot.Before(now) {
+		slot = now // Idle: leave at once
+	}
+
+	// Calls that would be waiting, counting this one
+	step := max(l.interval, 1)
+	waiting := int((slot.Sub(now) + step - 1) / step)
+	if waiting > l.depth {
+		l.mu.Unlock()
+		l.opts.observe(false, 0, 0)
+		return ErrTooManyCalls
+	}
+
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	free := uint(l.depth - waiting)
+
+	wait := slot.Sub(now)
+	if wait <= 0 {
+		l.opts.observe(true, free, 0)
+		return nil
+	}
+
+	timer := l.opts.clock.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C():
+		l.opts.observe(true, free, wait)
+		return nil
+	case <-ctx.Done():
+		l.opts.observe(false, free, l.opts.clock.Since(now))
+		return ctx.Err()
+	}
+}
+
+// Wrap returns an Effector that waits for its turn before calling effector.
+func (l *LeakyBucket) Wrap(effector Effector) Effector {
+	return func(ctx context.Context) (string, error) {
+		if err := l.Wait(ctx); err != nil {
+			return "", err
+		}
+
+		return effector(ctx)
+	}
+}
@@ -0,0 +1,27 @@
+package throttle
+
+import "context"
+
+// Limiter decides whether a call may proceed now. It is implemented by
+// TokenBucket, GCRA and the sliding window limiters, so callers that only
+// need an admission decision can switch algorithms without changing code.
+type Limiter interface {
+	// Allow reports whether a call may proceed, and counts it if so.
+	Allow() bool
+}
+
+// Wrap applies l to effector: calls that l does not allow are rejected with
+// ErrTooManyCalls.
+func Wrap(l Limiter, effector Effector) Effector {
+	return func(ctx context.Context) (string, error) {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+
+		if !l.Allow() {
+			return "", ErrTooManyCalls
+		}
+
+		return effector(ctx)
+	}
+}
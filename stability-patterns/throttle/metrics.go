@@ -0,0 +1,119 @@
+package throttle
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// Decision describes one admission decision made by a limiter.
+type Decision struct {
+	Allowed bool          // whether the call was let through
+	Tokens  uint          // tokens left afterwards; see below
+	Wait    time.Duration // time the call waited before the decision
+}
+
+// Metrics receives every Decision of a limiter configured WithMetrics, to
+// graph saturation and tune limits. Observe is called synchronously on the
+// call path, sometimes under the limiter's lock, so it must be fast.
+//
+// Tokens is the bucket's level for token buckets, the quota left in the
+// window for sliding windows, and the free places in the queue for a
+// LeakyBucket. Hierarchical and DualRate report their global and peak
+// buckets.
+type Metrics interface {
+	Observe(Decision)
+}
+
+// WithMetrics reports every admission decision to m.
+func WithMetrics(m Metrics) Option {
+	return func(o *options) {
+		o.metrics = m
+	}
+}
+
+// observe reports a decision to the metrics hook, if any.
+func (o options) observe(allowed bool, tokens uint, wait time.Duration) {
+	if o.metrics != nil {
+		o.metrics.Observe(Decision{Allowed: allowed, Tokens: tokens, Wait: wait})
+	}
+}
+
+// Counts holds the totals gathered by Stats.
+type Counts struct {
+	Allowed  uint64        `json:"allowed"`
+	Rejected uint64        `json:"rejected"`
+	Waits    uint64        `json:"waits"`  // decisions that waited at all
+	Waited   time.Duration `json:"waited"` // total time waited
+	Tokens   uint          `json:"tokens"` // level at the last decision
+}
+
+// Stats is a Metrics that keeps running totals and the last token level.
+// Its zero value is ready to use. Share one Stats only between limiters
+// whose token levels mean the same thing.
+type Stats struct {
+	allowed  atomic.Uint64
+	rejected atomic.Uint64
+	waits    atomic.Uint64
+	waited   atomic.Int64
+	tokens   atomic.Uint64
+}
+
+// Observe adds d to the totals.
+func (s *Stats) Observe(d Decision) {
+	if d.Allowed {
+		s.allowed.Add(1)
+	} else {
+		s.rejected.Add(1)
+	}
+	if d.Wait > 0 {
+		s.waits.Add(1)
+		s.waited.Add(int64(d.Wait))
+	}
+	s.tokens.Store(uint64(d.Tokens))
+}
+
+// Counts returns the current totals.
+func (s *Stats) Counts() Counts {
+	return Counts{
+		Allowed:  s.allowed.Load(),
+		Rejected: s.rejected.Load(),
+		Waits:    s.waits.Load(),
+		Waited:   time.Duration(s.waited.Load()),
+		Tokens:   uint(s.tokens.Load()),
+	}
+}
+
+// Var returns an expvar.Var reporting the current Counts as JSON, to be
+// published with expvar.Publish.
+func (s *Stats) Var() expvar.Var {
+	return expvar.Func(func() any { return s.Counts() })
+}
+
+// WritePrometheus writes the current Counts to w in the Prometheus text
+// exposition format, with metric names starting with prefix, such as
+// "api_throttle". Serve it from a /metrics handler.
+func (s *Stats) WritePrometheus(w io.Writer, prefix string) error {
+	c := s.Counts()
+
+	_, err := fmt.Fprintf(w, `# HELP %[1]s_allowed_total Calls let through by the limiter.
+# TYPE %[1]s_allowed_total counter
+%[1]s_allowed_total %[2]d
+# HELP %[1]s_rejected_total Calls rejected by the limiter.
+# TYPE %[1]s_rejected_total counter
+%[1]s_rejected_total %[3]d
+# HELP %[1]s_wait_seconds_total Time calls waited for the limiter.
+# TYPE %[1]s_wait_seconds_total counter
+%[1]s_wait_seconds_total %[4]g
+# HELP %[1]s_waits_total Calls that waited for the limiter.
+# TYPE %[1]s_waits_total counter
+%[1]s_waits_total %[5]d
+# HELP %[1]s_tokens Tokens left at the last decision.
+# TYPE %[1]s_tokens gauge
+%[1]s_tokens %[6]d
+`, prefix, c.Allowed, c.Rejected, c.Waited.Seconds(), c.Waits, c.Tokens)
+
+	return err
+}
@@ -0,0 +1,87 @@
+package throttle
+
+import (
+	"math"
+
+	"github.com/1core-dev/cloud-native/stability-patterns/clock"
+)
+
+// Option configures optional limiter behavior.
+type Option func(*options)
+
+type options struct {
+	clock   clock.Clock
+	drained func() bool
+	metrics Metrics
+	reserve map[Priority]float64
+}
+
+// buildOptions applies opts over the defaults.
+func buildOptions(opts []Option) options {
+	o := options{clock: clock.Real}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithClock sets the clock used to refill tokens and time out waiters. The
+// default is clock.Real; tests can pass a *clock.Fake to refill on demand.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// WithDrain empties the limiter whenever drained reports true, as if a burst
+// had used up every token; tokens refill normally once it reports false. It
+// is a failure-injection hook for tests of degraded behavior.
+func WithDrain(drained func() bool) Option {
+	return func(o *options) {
+		o.drained = drained
+	}
+}
+
+// WithReserve keeps the last tokens of the bucket for important calls:
+// reserve maps a priority to the fraction of the bucket's capacity that must
+// remain for calls of that priority to get a token, so they are turned away
+// first as tokens run low. Priorities are read from the call's context, as
+// set by WithPriority.
+//
+// For instance, {Low: 0.5, Normal: 0.2} admits Low calls while more than
+// half the tokens remain, Normal ones while more than a fifth do, and High
+// and Critical ones down to the last token. Fractions are clamped to [0, 1].
+func WithReserve(reserve map[Priority]float64) Option {
+	return func(o *options) {
+		o.reserve = reserve
+	}
+}
+
+// admits reports whether a call of priority p may take one of tokens, out
+// of a capacity of max, given the reserve.
+func (o options) admits(p Priority, tokens, max uint) bool {
+	return o.admitsN(p, tokens, max, 1)
+}
+
+// admitsN is admits for a call costing n tokens.
+func (o options) admitsN(p Priority, tokens, max, n uint) bool {
+	reserved := o.reserved(p, max)
+	return tokens >= reserved && tokens-reserved >= n
+}
+
+// reserved returns how many of max tokens the reserve keeps from calls of
+// priority p.
+func (o options) reserved(p Priority, max uint) uint {
+	f := o.reserve[p]
+	if !(f > 0) {
+		return 0 // Also for NaN, which would convert to an arbitrary uint
+	}
+	return uint(math.Ceil(min(f, 1) * float64(max)))
+}
+
+// drain empties b if the drain hook says so.
+func (o options) drain(b *bucket) {
+	if o.drained != nil && o.drained() {
+		b.tokens = 0
+	}
+}
@@ -0,0 +1,267 @@
+package throttle
+
+import (
+	"container/heap"
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/1core-dev/cloud-native/stability-patterns/clock"
+)
+
+// Priority ranks calls competing for scarce tokens; higher values are
+// admitted first.
+type Priority int
+
+// Common priority levels. Calls without a priority are Normal.
+const (
+	Low      Priority = 0
+	Normal   Priority = 1
+	High     Priority = 2
+	Critical Priority = 3
+)
+
+type priorityKey struct{}
+
+// WithPriority returns a copy of ctx carrying p.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFromContext returns the priority carried by ctx, or Normal.
+func PriorityFromContext(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	return Normal
+}
+
+// PriorityMiddleware returns an http.Handler that reads an integer priority
+// from the named request header and stores it in the request context for
+// the PriorityLimiter. Requests without a valid header keep Normal, and
+// values outside Low..Critical are clamped to that range.
+//
+// Any client can set the header and claim Critical, bypassing reserves, so
+// install the middleware only behind an authenticated or internal hop that
+// sets or strips the header itself.
+func PriorityMiddleware(header string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v, err := strconv.Atoi(r.Header.Get(header)); err == nil {
+			p := Priority(min(max(v, int(Low)), int(Critical)))
+			r = r.WithContext(WithPriority(r.Context(), p))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// PriorityCounts holds per-priority admission statistics.
+type PriorityCounts struct {
+	Admitted uint64
+	Rejected uint64
+}
+
+// PriorityLimiter is a token bucket that, when tokens run out, queues calls
+// for up to maxWait and hands refilled tokens to the highest-priority
+// waiters first, rather than rejecting in arrival order. Waiters of equal
+// priority are served first come, first served.
+type PriorityLimiter struct {
+	maxWait time.Duration
+	opts    options
+
+	mu      sync.Mutex
+	bucket  *bucket
+	waiters waitHeap
+	seq     uint64      // arrival counter for FIFO order within a priority
+	timer   clock.Timer // wakes up the queue at the next refill
+	counts  map[Priority]PriorityCounts
+}
+
+// NewPriorityLimiter returns a PriorityLimiter for limit in which callers
+// wait at most maxWait for a token.
+func NewPriorityLimiter(limit Limit, maxWait time.Duration, opts ...Option) *PriorityLimiter {
+	o := buildOptions(opts)
+
+	return &PriorityLimiter{
+		maxWait: maxWait,
+		opts:    o,
+		bucket:  newBucket(limit, o.clock.Now()),
+		counts:  make(map[Priority]PriorityCounts),
+	}
+}
+
+// Wrap returns an Effector that waits for a token, ranked by the priority in
+// its context, before calling effector.
+func (l *PriorityLimiter) Wrap(effector Effector) Effector {
+	return func(ctx context.Context) (string, error) {
+		if err := l.Wait(ctx); err != nil {
+			return "", err
+		}
+
+		return effector(ctx)
+	}
+}
+
+// Wait takes a token, queueing behind higher-priority callers if none is
+// available. It returns ErrTooManyCalls if no token was granted within
+// maxWait, or ctx.Err() if ctx is done first.
+func (l *PriorityLimiter) Wait(ctx context.Context) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	p := PriorityFromContext(ctx)
+
+	l.mu.Lock()
+
+	l.bucket.advance(l.opts.clock.Now())
+	l.opts.drain(l.bucket)
+
+	// Fast path: a token is free and nobody is waiting for it
+	if len(l.waiters) == 0 && l.opts.admits(p, l.bucket.tokens, l.bucket.limit.Max) {
+		l.bucket.tokens--
+		l.count(p, true)
+		left := l.bucket.tokens
+		l.mu.Unlock()
+		l.opts.observe(true, left, 0)
+		return nil
+	}
+
+	if l.maxWait <= 0 {
+		l.count(p, false)
+		l.mu.Unlock()
+		l.opts.observe(false, 0, 0)
+		return ErrTooManyCalls
+	}
+
+	start := l.opts.clock.Now()
+
+	w := &waiter{priority: p, seq: l.seq, ready: make(chan struct{})}
+	l.seq++
+	heap.Push(&l.waiters, w)
+	l.grant()
+
+	l.mu.Unlock()
+
+	timeout := l.opts.clock.NewTimer(l.maxWait)
+	defer timeout.Stop()
+
+	var err error
+
+	select {
+	case <-w.ready:
+		l.opts.observe(true, 0, l.opts.clock.Since(start)) // Counted when granted
+		return nil
+	case <-timeout.C():
+		err = ErrTooManyCalls
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	l.mu.Lock()
+	granted := w.index < 0 // Granted while we were giving up; keep the token
+	if !granted {
+		heap.Remove(&l.waiters, w.index)
+		l.count(p, false)
+	}
+	l.mu.Unlock()
+
+	l.opts.observe(granted, 0, l.opts.clock.Since(start))
+	if granted {
+		return nil
+	}
+	return err
+}
+
+// Counts returns the admission statistics for every priority seen so far.
+func (l *PriorityLimiter) Counts() map[Priority]PriorityCounts {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	counts := make(map[Priority]PriorityCounts, len(l.counts))
+	for p, c := range l.counts {
+		counts[p] = c
+	}
+	return counts
+}
+
+// grant hands available tokens to waiters in priority order and schedules
+// the next wake-up if some are left waiting. Callers must hold l.mu.
+func (l *PriorityLimiter) grant() {
+	l.bucket.advance(l.opts.clock.Now())
+	l.opts.drain(l.bucket)
+
+	for len(l.waiters) > 0 && l.opts.admits(l.waiters[0].priority, l.bucket.tokens, l.bucket.limit.Max) {
+		w := heap.Pop(&l.waiters).(*waiter)
+		l.bucket.tokens--
+		l.count(w.priority, true)
+		close(w.ready)
+	}
+
+	if len(l.waiters) == 0 || l.timer != nil || l.bucket.limit.Interval <= 0 {
+		return
+	}
+
+	next := l.bucket.last.Add(l.bucket.limit.Interval).Sub(l.opts.clock.Now())
+	l.timer = l.opts.clock.AfterFunc(next, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		l.timer = nil
+		l.grant()
+	})
+}
+
+// count records an admission decision. Callers must hold l.mu.
+func (l *PriorityLimiter) count(p Priority, admitted bool) {
+	c := l.counts[p]
+	if admitted {
+		c.Admitted++
+	} else {
+		c.Rejected++
+	}
+	l.counts[p] = c
+}
+
+// waiter is a caller queued for a token.
+type waiter struct {
+	priority Priority
+	seq      uint64
+	ready    chan struct{} // closed when a token is granted
+	index    int           // position in the heap, -1 once removed
+}
+
+// waitHeap orders waiters by priority, then by arrival.
+type waitHeap []*waiter
+
+func (h waitHeap) Len() int { return len(h) }
+
+func (h waitHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h waitHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *waitHeap) Push(x any) {
+	w := x.(*waiter)
+	w.index = len(*h)
+	*h = append(*h, w)
+}
+
+func (h *waitHeap) Pop() any {
+	old := *h
+	n := len(old)
+	w := old[n-1]
+	w.index = -1
+	*h = old[:n-1]
+	return w
+}
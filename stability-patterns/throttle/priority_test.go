@@ -0,0 +1,29 @@
+package throttle
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPriorityMiddlewareClamps(t *testing.T) {
+	var got Priority
+	h := PriorityMiddleware("X-Priority", http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = PriorityFromContext(r.Context())
+	}))
+
+	for header, want := range map[string]Priority{
+		"":     Normal,
+		"nope": Normal,
+		"2":    High,
+		"-5":   Low,
+		"99":   Critical,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-Priority", header)
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		if got != want {
+			t.Errorf("X-Priority %q = %v, want %v", header, got, want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package throttle
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// LimiterSpec declares a named limiter: a token bucket of Burst tokens,
+// refilled by Rate tokens every Interval, kept per client key.
+//
+// Key selects the client key of an HTTP request: "" for a single bucket
+// shared by every request, "ip" for ByIP, "bearer" for ByBearerToken, or
+// "header:Name" for ByHeader(Name). Idle is passed to NewKeyedThrottle.
+//
+// In JSON, durations are strings such as "1s":
+//
+//	{"rate": 10, "burst": 20, "interval": "1s", "key": "header:X-API-Key"}
+type LimiterSpec struct {
+	Rate     uint
+	Burst    uint
+	Interval time.Duration // defaults to one second
+	Key      string
+	Idle     time.Duration
+}
+
+// UnmarshalJSON decodes a LimiterSpec, parsing its durations.
+func (s *LimiterSpec) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Rate     uint   `json:"rate"`
+		Burst    uint   `json:"burst"`
+		Interval string `json:"interval"`
+		Key      string `json:"key"`
+		Idle     string `json:"idle"`
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields() // Catch typos in knob names
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+
+	spec := LimiterSpec{Rate: raw.Rate, Burst: raw.Burst, Key: raw.Key}
+	for _, d := range []struct {
+		s   string
+		dst *time.Duration
+	}{{raw.Interval, &spec.Interval}, {raw.Idle, &spec.Idle}} {
+		if d.s == "" {
+			continue
+		}
+
+		v, err := time.ParseDuration(d.s)
+		if err != nil {
+			return err
+		}
+		*d.dst = v
+	}
+
+	*s = spec
+	return nil
+}
+
+// requestKey returns the RequestKeyFunc that s.Key names.
+func (s LimiterSpec) requestKey() (RequestKeyFunc, error) {
+	switch key := s.Key; {
+	case key == "":
+		return func(*http.Request) string { return "" }, nil
+	case key == "ip":
+		return ByIP, nil
+	case key == "bearer":
+		return ByBearerToken, nil
+	case strings.HasPrefix(key, "header:") && len(key) > len("header:"):
+		return ByHeader(key[len("header:"):]), nil
+	default:
+		return nil, fmt.Errorf("unknown key %q", key)
+	}
+}
+
+// Named is a limiter created by a Registry from its LimiterSpec.
+type Named struct {
+	Name string
+	Spec LimiterSpec
+
+	Throttle *KeyedThrottle
+	Key      RequestKeyFunc // the extractor that Spec.Key names
+	Stats    *Stats         // the decisions of Throttle so far
+}
+
+// Handler returns Middleware applying the limiter to next, keying requests
+// as its spec says.
+func (n *Named) Handler(rateLimitHeaders bool, next http.Handler) http.Handler {
+	return Middleware(n.Throttle, n.Key, rateLimitHeaders, next)
+}
+
+// Registry holds the limiters of a service by name, created from one
+// declarative configuration, so every throttle is defined in one place and
+// can be inspected at runtime. It is safe for concurrent use.
+type Registry struct {
+	limiters map[string]*Named
+}
+
+// NewRegistry creates a limiter for every spec, passing opts to each. Each
+// limiter records its decisions in its own Stats, overriding WithMetrics. If
+// any spec is invalid, it returns every error found and no registry.
+func NewRegistry(specs map[string]LimiterSpec, opts ...Option) (*Registry, error) {
+	reg := &Registry{limiters: make(map[string]*Named, len(specs))}
+
+	var errs []error
+	for name, spec := range specs {
+		if spec.Interval == 0 {
+			spec.Interval = time.Second
+		}
+
+		key, err := spec.requestKey()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("limiter %q: %w", name, err))
+			continue
+		}
+		if spec.Burst < 1 {
+			errs = append(errs, fmt.Errorf("limiter %q: burst must be at least 1", name))
+			continue
+		}
+		if spec.Interval < 0 {
+			errs = append(errs, fmt.Errorf("limiter %q: interval must be positive", name))
+			continue
+		}
+
+		stats := new(Stats)
+		limit := Limit{Max: spec.Burst, Refill: spec.Rate, Interval: spec.Interval}
+		reg.limiters[name] = &Named{
+			Name:     name,
+			Spec:     spec,
+			Throttle: NewKeyedThrottle(limit, spec.Idle, append(slices.Clip(opts), WithMetrics(stats))...),
+			Key:      key,
+			Stats:    stats,
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return reg, nil
+}
+
+// LoadRegistry is NewRegistry for a JSON document mapping limiter names to
+// LimiterSpecs.
+func LoadRegistry(r io.Reader, opts ...Option) (*Registry, error) {
+	var specs map[string]LimiterSpec
+	if err := json.NewDecoder(r).Decode(&specs); err != nil {
+		return nil, fmt.Errorf("parse limiters: %w", err)
+	}
+
+	return NewRegistry(specs, opts...)
+}
+
+// Get returns the limiter named name.
+func (reg *Registry) Get(name string) (*Named, bool) {
+	n, ok := reg.limiters[name]
+	return n, ok
+}
+
+// Names returns the names of the registry's limiters, sorted.
+func (reg *Registry) Names() []string {
+	names := make([]string, 0, len(reg.limiters))
+	for name := range reg.limiters {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Counts returns the current Counts of every limiter by name, for an admin
+// endpoint or expvar.
+func (reg *Registry) Counts() map[string]Counts {
+	counts := make(map[string]Counts, len(reg.limiters))
+	for name, n := range reg.limiters {
+		counts[name] = n.Stats.Counts()
+	}
+	return counts
+}
@@ -0,0 +1,189 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// TokenStore holds token buckets by key, so that a quota can be kept outside
+// the process and shared by every replica of a service.
+//
+// Implementations refill buckets lazily from the time elapsed since their
+// last refill, and take tokens atomically with the refill.
+type TokenStore interface {
+	// TakeN refills the bucket for key according to limit, creating it
+	// full if needed, and takes n tokens from it if it holds that many. It
+	// reports whether the tokens were taken.
+	TakeN(ctx context.Context, key string, n uint, limit Limit) (bool, error)
+}
+
+// MemoryTokenStore is a TokenStore that keeps buckets in process memory.
+// It only shares a quota between the users of one process.
+type MemoryTokenStore struct {
+	opts options
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// NewMemoryTokenStore returns an empty MemoryTokenStore.
+func NewMemoryTokenStore(opts ...Option) *MemoryTokenStore {
+	return &MemoryTokenStore{opts: buildOptions(opts), buckets: make(map[string]*bucket)}
+}
+
+// TakeN takes n tokens from the bucket for key if it holds that many.
+func (s *MemoryTokenStore) TakeN(_ context.Context, key string, n uint, limit Limit) (bool, error) {
+	now := s.opts.clock.Now()
+
+	s.mu.Lock()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = newBucket(limit, now)
+		s.buckets[key] = b
+	}
+	b.limit = limit // Follow limit changes
+	b.tokens = min(b.tokens, limit.Max)
+
+	b.advance(now)
+	s.opts.drain(b)
+
+	ok = b.tokens >= n
+	if ok {
+		b.tokens -= n
+	}
+	left := b.tokens
+
+	s.mu.Unlock()
+
+	s.opts.observe(ok, left, 0)
+	return ok, nil
+}
+
+// Evaluator runs a Lua script on a Redis server, as EVAL does, and returns
+// its reply. It is small enough to adapt any Redis client to; with
+// go-redis, for instance:
+//
+//	func (c client) Eval(ctx context.Context, script string, keys []string, args ...any) (any, error) {
+//		return c.Client.Eval(ctx, script, keys, args...).Result()
+//	}
+type Evaluator interface {
+	Eval(ctx context.Context, script string, keys []string, args ...any) (any, error)
+}
+
+// takeScript refills and takes from a bucket stored as a Redis hash, in one
+// atomic step. Time comes from the server, so replicas with skewed clocks
+// agree. The key expires once the bucket would be full again, since a full
+// bucket is the same as a missing one.
+const takeScript = `
+local max, refill, interval, n = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3]), tonumber(ARGV[4])
+local t = redis.call('TIME')
+local now = t[1] * 1000000 + t[2]
+
+local b = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens, last = tonumber(b[1]), tonumber(b[2])
+if tokens == nil then
+	tokens, last = max, now
+end
+tokens = math.min(tokens, max)
+
+if interval > 0 then
+	local k = math.floor((now - last) / interval)
+	if k > 0 then
+		tokens = math.min(max, tokens + k * refill)
+		last = last + k * interval
+	end
+end
+
+local ok = 0
+if tokens >= n then
+	tokens = tokens - n
+	ok = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tokens, 'last', last)
+if interval > 0 and refill > 0 then
+	local refills = math.ceil((max - tokens) / refill) + 1
+	redis.call('PEXPIRE', KEYS[1], math.ceil(refills * interval / 1000))
+end
+return ok
+`
+
+// RedisTokenStore is a TokenStore that keeps buckets in Redis, so every
+// replica connected to the same server shares them. Each TakeN is a single
+// atomic script call.
+type RedisTokenStore struct {
+	eval   Evaluator
+	prefix string
+}
+
+// NewRedisTokenStore returns a RedisTokenStore that runs its script through
+// eval and stores the bucket of key under prefix+key.
+func NewRedisTokenStore(eval Evaluator, prefix string) *RedisTokenStore {
+	return &RedisTokenStore{eval: eval, prefix: prefix}
+}
+
+// TakeN takes n tokens from the bucket for key if it holds that many.
+func (s *RedisTokenStore) TakeN(ctx context.Context, key string, n uint, limit Limit) (bool, error) {
+	reply, err := s.eval.Eval(ctx, takeScript, []string{s.prefix + key},
+		limit.Max, limit.Refill, limit.Interval.Microseconds(), n)
+	if err != nil {
+		return false, err
+	}
+
+	ok, isInt := reply.(int64)
+	if !isInt {
+		return false, fmt.Errorf("throttle: unexpected script reply %T", reply)
+	}
+	return ok == 1, nil
+}
+
+// ErrStoreUnavailable wraps the error of a TokenStore that could not be
+// consulted.
+var ErrStoreUnavailable = errors.New("token store unavailable")
+
+// Shared applies one Limit per key to calls, with the buckets held in a
+// TokenStore.
+type Shared struct {
+	store TokenStore
+	limit Limit
+}
+
+// NewShared returns a Shared limiter keeping its buckets in store.
+func NewShared(store TokenStore, limit Limit) *Shared {
+	return &Shared{store: store, limit: limit}
+}
+
+// AllowN takes n tokens from key's bucket and reports whether they were
+// available. Callers that prefer to fail open when the store is down can
+// admit calls on error.
+func (s *Shared) AllowN(ctx context.Context, key string, n uint) (bool, error) {
+	ok, err := s.store.TakeN(ctx, key, n, s.limit)
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", ErrStoreUnavailable, err)
+	}
+	return ok, nil
+}
+
+// Wrap returns an Effector taking one token per call from the bucket that
+// key selects. Calls without a token are rejected with ErrTooManyCalls,
+// and calls the store could not decide on with ErrStoreUnavailable.
+func (s *Shared) Wrap(effector Effector, key KeyFunc) Effector {
+	return func(ctx context.Context) (string, error) {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+
+		ok, err := s.AllowN(ctx, key(ctx), 1)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return "", ErrTooManyCalls
+		}
+
+		return effector(ctx)
+	}
+}
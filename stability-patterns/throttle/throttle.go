@@ -1,14 +1,16 @@
-// Package throttle provides a token bucket rate limiter for effectful functions.
+// Package throttle provides token bucket and leaky bucket rate limiters for effectful functions.
 // It bounds execution rate to prevent overload and smooth traffic spikes.
 package throttle
 
 import (
 	"context"
-	"fmt"
-	"sync"
+	"errors"
 	"time"
 )
 
+// ErrTooManyCalls signals that the call was rejected because no tokens remain.
+var ErrTooManyCalls = errors.New("too many calls")
+
 // Effector is a function that performs work under context control.
 type Effector func(context.Context) (string, error)
 
@@ -16,48 +18,27 @@ type Effector func(context.Context) (string, error)
 //
 // It allows up to max calls in burst, with refill tokens added every interval.
 // If no tokens remain, the call is rejected.
-func Throttle(effector Effector, max uint, refill uint, d time.Duration) Effector {
-	var (
-		tokens = max // current token count
-		once   sync.Once
-		mu     sync.Mutex
-	)
+//
+// Tokens are refilled lazily from the time elapsed, as by a TokenBucket, so
+// no background goroutine is involved and the limit holds however callers'
+// contexts end. Use a TokenBucket directly to share it or weigh calls.
+//
+// The result may be of any type, so functions returning structs, responses,
+// or rows can be throttled directly; an Effector is accepted too.
+func Throttle[T any](effector func(context.Context) (T, error), max uint, refill uint, d time.Duration, opts ...Option) func(context.Context) (T, error) {
+	l := NewTokenBucket(Limit{Max: max, Refill: refill, Interval: d}, opts...)
 
-	return func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (T, error) {
 		if ctx.Err() != nil {
-			return "", ctx.Err()
+			var zero T
+			return zero, ctx.Err()
 		}
 
-		// Start background refill loop once
-		once.Do(func() {
-			ticker := time.NewTicker(d)
-
-			go func() {
-				defer ticker.Stop()
-
-				for {
-					select {
-					case <-ctx.Done():
-						return
-					case <-ticker.C:
-						mu.Lock()
-						t := min(tokens+refill, max)
-						tokens = t
-						mu.Unlock()
-					}
-
-				}
-			}()
-		})
-		mu.Lock()
-		defer mu.Unlock()
-
-		if tokens <= 0 {
-			return "", fmt.Errorf("too many calls")
+		if !l.AllowN(ctx, 1) {
+			var zero T
+			return zero, ErrTooManyCalls
 		}
 
-		tokens--
 		return effector(ctx)
 	}
-
 }
@@ -0,0 +1,108 @@
+package throttle
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// The benchmarks below cover the wrappers that sit on every request. Each
+// reports allocations, which should stay at zero per call.
+
+// plenty is a limit generous enough that benchmarks measure the allowed path.
+var plenty = Limit{Max: 1 << 30, Refill: 1 << 30, Interval: time.Second}
+
+func noop(context.Context) (string, error) { return "", nil }
+
+func BenchmarkThrottle(b *testing.B) {
+	f := Throttle(noop, plenty.Max, plenty.Refill, plenty.Interval)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		f(ctx)
+	}
+}
+
+func BenchmarkThrottleRejected(b *testing.B) {
+	f := Throttle(noop, 1, 1, time.Hour)
+	ctx := context.Background()
+	f(ctx) // Use up the only token
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		f(ctx)
+	}
+}
+
+func BenchmarkThrottleParallel(b *testing.B) {
+	f := Throttle(noop, plenty.Max, plenty.Refill, plenty.Interval)
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		ctx := context.Background()
+		for pb.Next() {
+			f(ctx)
+		}
+	})
+}
+
+func BenchmarkTokenBucketAllowN(b *testing.B) {
+	l := NewTokenBucket(plenty)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.AllowN(ctx, 1)
+	}
+}
+
+func BenchmarkKeyedThrottle(b *testing.B) {
+	k := NewKeyedThrottle(plenty, 0)
+	f := k.Wrap(noop, func(context.Context) string { return "tenant" })
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		f(ctx)
+	}
+}
+
+func BenchmarkHierarchical(b *testing.B) {
+	f := Hierarchical(noop, func(context.Context) string { return "tenant" }, plenty, plenty)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		f(ctx)
+	}
+}
+
+func BenchmarkDualRate(b *testing.B) {
+	d := NewDualRate(plenty, plenty)
+	f := d.Wrap(noop, func(context.Context) string { return "tenant" })
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		f(ctx)
+	}
+}
+
+func BenchmarkGCRA(b *testing.B) {
+	l := NewGCRA(time.Nanosecond, 1<<30)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.Allow()
+	}
+}
+
+func BenchmarkSlidingWindow(b *testing.B) {
+	w := NewSlidingWindow(1<<30, time.Second)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w.Allow()
+	}
+}
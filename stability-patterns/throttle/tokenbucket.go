@@ -0,0 +1,334 @@
+package throttle
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/1core-dev/cloud-native/stability-patterns/clock"
+)
+
+// costKey is the context key for the cost of a call.
+type costKey struct{}
+
+// WithCost returns a copy of ctx declaring that the call costs n tokens,
+// for limiters that weigh calls, such as TokenBucket.Wrap.
+func WithCost(ctx context.Context, n uint) context.Context {
+	return context.WithValue(ctx, costKey{}, n)
+}
+
+// CostFromContext returns the cost declared in ctx, or 1.
+func CostFromContext(ctx context.Context) uint {
+	if n, ok := ctx.Value(costKey{}).(uint); ok {
+		return n
+	}
+	return 1
+}
+
+// TokenBucket is a token bucket limiter that weighs calls: expensive
+// operations can take several tokens and cheap ones a single token, so one
+// bucket governs requests of different weights. It refills lazily from the
+// time elapsed, without a background goroutine.
+//
+// Callers can either be turned away at once with AllowN, or wait their turn
+// with WaitN. Waiters of the same priority are served in arrival order, and
+// AllowN does not jump ahead of waiters of its priority or higher, so no
+// caller is starved by later ones of its rank. A waiter held back, such as a
+// Low one kept out by WithReserve, doesn't hold up higher priorities, which
+// may pass it. Schedulers that plan work ahead can instead book future
+// tokens with Reserve.
+//
+// It is safe for concurrent use.
+type TokenBucket struct {
+	opts options
+
+	mu      sync.Mutex
+	bucket  *bucket
+	waiters list.List   // of *costWaiter, oldest first
+	timer   clock.Timer // wakes up the queue at the next refill
+}
+
+// costWaiter is a caller queued by WaitN.
+type costWaiter struct {
+	cost     uint
+	priority Priority
+	ready    chan struct{} // closed when the tokens are granted
+	granted  bool
+}
+
+// NewTokenBucket returns a full TokenBucket for limit.
+func NewTokenBucket(limit Limit, opts ...Option) *TokenBucket {
+	o := buildOptions(opts)
+	return &TokenBucket{opts: o, bucket: newBucket(limit, o.clock.Now())}
+}
+
+// Allow takes one token, reporting whether one was available. It makes
+// TokenBucket a Limiter.
+func (l *TokenBucket) Allow() bool {
+	return l.AllowN(context.Background(), 1)
+}
+
+// AllowN takes cost tokens at once if that many are available and nobody of
+// the same priority or higher is waiting for them, and reports whether it
+// did; either all of them are taken or none. A call costing more than the
+// bucket holds is never allowed. The priority in ctx applies to WithReserve.
+func (l *TokenBucket) AllowN(ctx context.Context, cost uint) bool {
+	now := l.opts.clock.Now()
+
+	l.mu.Lock()
+
+	l.bucket.advance(now)
+	l.opts.drain(l.bucket)
+
+	p := PriorityFromContext(ctx)
+	ok := !l.queued(p) && l.opts.admitsN(p, l.bucket.tokens, l.bucket.limit.Max, cost)
+	if ok {
+		l.bucket.tokens -= cost
+	}
+	left := l.bucket.tokens
+
+	l.mu.Unlock()
+
+	l.opts.observe(ok, left, 0)
+	return ok
+}
+
+// WaitN takes cost tokens, waiting behind earlier callers of the same
+// priority or higher until they are available. It returns ctx.Err() if ctx
+// is done first, in which case no tokens are taken, and ErrTooManyCalls at
+// once if cost exceeds what the reserve for ctx's priority leaves of the
+// bucket's capacity, since such a call could never be served and would hold
+// up everyone queued behind it.
+func (l *TokenBucket) WaitN(ctx context.Context, cost uint) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	start := l.opts.clock.Now()
+	p := PriorityFromContext(ctx)
+
+	l.mu.Lock()
+
+	if l.opts.reserved(p, l.bucket.limit.Max)+cost > l.bucket.limit.Max {
+		l.mu.Unlock()
+		l.opts.observe(false, 0, 0)
+		return ErrTooManyCalls
+	}
+
+	l.bucket.advance(start)
+	l.opts.drain(l.bucket)
+
+	// Fast path: enough tokens and nobody ahead
+	if !l.queued(p) && l.opts.admitsN(p, l.bucket.tokens, l.bucket.limit.Max, cost) {
+		l.bucket.tokens -= cost
+		left := l.bucket.tokens
+		l.mu.Unlock()
+		l.opts.observe(true, left, 0)
+		return nil
+	}
+
+	w := &costWaiter{cost: cost, priority: p, ready: make(chan struct{})}
+	e := l.waiters.PushBack(w)
+	l.grant()
+
+	l.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		l.opts.observe(true, 0, l.opts.clock.Since(start))
+		return nil
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	granted := w.granted // Granted while we were giving up; keep the tokens
+	if !granted {
+		l.waiters.Remove(e)
+		l.grant() // Callers behind may fit now
+	}
+	l.mu.Unlock()
+
+	l.opts.observe(granted, 0, l.opts.clock.Since(start))
+	if granted {
+		return nil
+	}
+	return ctx.Err()
+}
+
+// queued reports whether a waiter of priority p or higher is waiting.
+// Callers must hold l.mu.
+func (l *TokenBucket) queued(p Priority) bool {
+	for e := l.waiters.Front(); e != nil; e = e.Next() {
+		if e.Value.(*costWaiter).priority >= p {
+			return true
+		}
+	}
+	return false
+}
+
+// Reserve books cost tokens now, taking them ahead of the refills that will
+// provide them if need be, and returns a Reservation telling how long to wait
+// before acting. It never blocks, so a scheduler can plan work instead of
+// retrying AllowN. The priority in ctx applies to WithReserve.
+//
+// Reservations are made in the order Reserve is called; they are not queued
+// behind callers of WaitN, which wait for the tokens left after them. The
+// Reservation is not OK if the tokens could never be available: the cost
+// exceeds what the reserve leaves of the bucket, or the bucket never
+// refills.
+func (l *TokenBucket) Reserve(ctx context.Context, cost uint) *Reservation {
+	now := l.opts.clock.Now()
+	p := PriorityFromContext(ctx)
+
+	l.mu.Lock()
+
+	b := l.bucket
+	b.advance(now)
+	l.opts.drain(b)
+
+	r := &Reservation{l: l, cost: cost, at: now}
+
+	reserved := l.opts.reserved(p, b.limit.Max)
+	need := reserved + cost + b.debt // Tokens needed in hand first
+	switch {
+	case reserved+cost > b.limit.Max:
+	case b.tokens >= need:
+		b.tokens -= cost
+		r.ok = true
+	case b.limit.Interval > 0 && b.limit.Refill > 0:
+		r.at = now.Add(b.untilAdded(now, need-b.tokens))
+		if b.tokens >= cost {
+			b.tokens -= cost
+		} else {
+			b.debt += cost - b.tokens
+			b.tokens = 0
+		}
+		r.ok = true
+	}
+	left := b.tokens
+
+	l.mu.Unlock()
+
+	l.opts.observe(r.ok, left, r.at.Sub(now))
+	return r
+}
+
+// Reservation is a booking of tokens returned by TokenBucket.Reserve.
+type Reservation struct {
+	l    *TokenBucket
+	cost uint
+	at   time.Time // when the tokens are available
+	ok   bool
+
+	canceled bool // guarded by l.mu
+}
+
+// OK reports whether the tokens were booked. If not, Delay and Cancel have
+// no meaning.
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay returns how long the holder must wait before acting on the
+// reservation, or zero if it may act now.
+func (r *Reservation) Delay() time.Duration {
+	if !r.ok {
+		return 0
+	}
+	return max(r.at.Sub(r.l.opts.clock.Now()), 0)
+}
+
+// Cancel gives the booked tokens back, if their time has not come yet, so
+// that other callers can use them. Reservations made after it keep their
+// delay. Cancel may be called more than once.
+func (r *Reservation) Cancel() {
+	if !r.ok {
+		return
+	}
+
+	l := r.l
+	now := l.opts.clock.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if r.canceled || !now.Before(r.at) {
+		return
+	}
+	r.canceled = true
+
+	b := l.bucket
+	b.advance(now)
+	if b.debt >= r.cost {
+		b.debt -= r.cost
+	} else {
+		b.tokens = min(b.tokens+r.cost-b.debt, b.limit.Max)
+		b.debt = 0
+	}
+
+	l.grant() // Waiters may fit now
+}
+
+// Wrap returns an Effector that takes the cost declared in its context,
+// one token by default, before calling effector. Calls without enough
+// tokens are rejected with ErrTooManyCalls.
+func (l *TokenBucket) Wrap(effector Effector) Effector {
+	return func(ctx context.Context) (string, error) {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+
+		if !l.AllowN(ctx, CostFromContext(ctx)) {
+			return "", ErrTooManyCalls
+		}
+
+		return effector(ctx)
+	}
+}
+
+// grant hands available tokens to waiters in arrival order, and schedules
+// the next wake-up if some are left waiting. A waiter that doesn't fit holds
+// up the later ones of its priority or lower, but not higher ones. Callers
+// must hold l.mu.
+func (l *TokenBucket) grant() {
+	now := l.opts.clock.Now()
+	l.bucket.advance(now)
+	l.opts.drain(l.bucket)
+
+	var (
+		held    bool     // whether a waiter was left waiting
+		blocked Priority // the highest priority left waiting, if held
+	)
+	for e := l.waiters.Front(); e != nil; {
+		next := e.Next()
+		w := e.Value.(*costWaiter)
+
+		switch {
+		case held && w.priority <= blocked:
+			// Stays behind an earlier waiter of its rank or higher
+		case !l.opts.admitsN(w.priority, l.bucket.tokens, l.bucket.limit.Max, w.cost):
+			held, blocked = true, w.priority // Waits for more tokens
+		default:
+			l.bucket.tokens -= w.cost
+			l.waiters.Remove(e)
+			w.granted = true
+			close(w.ready)
+		}
+
+		e = next
+	}
+
+	next := l.bucket.untilRefill(now)
+	if l.waiters.Len() == 0 || l.timer != nil || next <= 0 {
+		return
+	}
+
+	l.timer = l.opts.clock.AfterFunc(next, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		l.timer = nil
+		l.grant()
+	})
+}
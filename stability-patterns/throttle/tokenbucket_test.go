@@ -0,0 +1,132 @@
+package throttle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/1core-dev/cloud-native/stability-patterns/clock"
+)
+
+func TestTokenBucketReservedWaiterDoesNotBlockHigher(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	l := NewTokenBucket(Limit{Max: 10, Refill: 1, Interval: time.Second},
+		WithClock(fc), WithReserve(map[Priority]float64{Low: 0.5}))
+	ctx := context.Background()
+
+	if !l.AllowN(ctx, 5) {
+		t.Fatal("AllowN(5) on a full bucket failed")
+	}
+
+	// Five tokens left: all of them reserved from Low calls
+	low := make(chan error, 1)
+	go func() { low <- l.WaitN(WithPriority(ctx, Low), 1) }()
+	fc.BlockUntil(1) // The queue's wake-up timer
+
+	if !l.AllowN(WithPriority(ctx, High), 1) {
+		t.Fatal("High AllowN refused behind a reserve-blocked Low waiter")
+	}
+	if err := l.WaitN(WithPriority(ctx, Critical), 1); err != nil {
+		t.Fatalf("Critical WaitN = %v", err)
+	}
+	if l.AllowN(WithPriority(ctx, Low), 1) {
+		t.Fatal("Low AllowN jumped ahead of a Low waiter")
+	}
+
+	select {
+	case err := <-low:
+		t.Fatalf("Low waiter served inside its reserve: %v", err)
+	default:
+	}
+
+	fc.Advance(3 * time.Second) // Back to six tokens, one above the reserve
+	if err := <-low; err != nil {
+		t.Fatalf("Low WaitN = %v", err)
+	}
+}
+
+func TestTokenBucketReserveClamped(t *testing.T) {
+	l := NewTokenBucket(Limit{Max: 2, Refill: 1, Interval: time.Second},
+		WithReserve(map[Priority]float64{Low: -1, High: 2}))
+	ctx := context.Background()
+
+	if l.AllowN(WithPriority(ctx, High), 1) {
+		t.Fatal("High AllowN passed a reserve above the whole bucket")
+	}
+	if !l.AllowN(WithPriority(ctx, Low), 2) {
+		t.Fatal("Low AllowN refused under a negative reserve")
+	}
+}
+
+func TestTokenBucketWaitersInArrivalOrder(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	l := NewTokenBucket(Limit{Max: 2, Refill: 1, Interval: time.Second}, WithClock(fc))
+	ctx := context.Background()
+
+	if !l.AllowN(ctx, 2) {
+		t.Fatal("AllowN(2) on a full bucket failed")
+	}
+
+	order := make(chan int, 2)
+	big := make(chan struct{})
+	go func() {
+		l.WaitN(ctx, 2)
+		order <- 2
+		close(big)
+	}()
+	fc.BlockUntil(1)
+	go func() {
+		l.WaitN(ctx, 1)
+		order <- 1
+	}()
+	for l.waiting() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	fc.Advance(time.Second) // One token: not enough for the first waiter
+	if l.AllowN(ctx, 1) {
+		t.Fatal("AllowN jumped the queue")
+	}
+	fc.Advance(time.Second)
+	<-big
+	fc.Advance(time.Second)
+
+	if first, second := <-order, <-order; first != 2 || second != 1 {
+		t.Fatalf("served cost %d then %d, want 2 then 1", first, second)
+	}
+}
+
+// waiting returns the number of queued waiters.
+func (l *TokenBucket) waiting() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.waiters.Len()
+}
+
+func TestTokenBucketReserve(t *testing.T) {
+	fc := clock.NewFake(time.Unix(0, 0))
+	l := NewTokenBucket(Limit{Max: 2, Refill: 1, Interval: time.Second}, WithClock(fc))
+	ctx := context.Background()
+
+	if r := l.Reserve(ctx, 2); !r.OK() || r.Delay() != 0 {
+		t.Fatalf("Reserve(2) on a full bucket: ok %v, delay %v; want now", r.OK(), r.Delay())
+	}
+
+	r := l.Reserve(ctx, 2)
+	if !r.OK() || r.Delay() != 2*time.Second {
+		t.Fatalf("Reserve(2) on an empty bucket: ok %v, delay %v; want 2s", r.OK(), r.Delay())
+	}
+	if !l.Reserve(ctx, 1).OK() {
+		t.Fatal("Reserve(1) behind a booking failed")
+	}
+
+	r.Cancel()
+	if l.AllowN(ctx, 1) {
+		t.Fatal("tokens of a later booking handed out")
+	}
+
+	if r := l.Reserve(ctx, 3); r.OK() {
+		t.Fatal("Reserve above the capacity succeeded")
+	}
+}
@@ -0,0 +1,116 @@
+package throttle
+
+import (
+	"sync"
+	"time"
+)
+
+// SlidingWindow allows up to limit calls in any window of the given length,
+// estimated from the counts of the current and previous fixed windows: the
+// previous count is weighted by how much of it still overlaps the sliding
+// window. It uses constant memory and matches quotas stated as "N requests
+// per minute" closely, without the bursts a fixed window allows at its
+// boundaries.
+//
+// It is safe for concurrent use.
+type SlidingWindow struct {
+	limit  int
+	window time.Duration
+	opts   options
+
+	mu        sync.Mutex
+	start     time.Time // start of the current fixed window
+	cur, prev int       // calls counted in the current and previous windows
+}
+
+// NewSlidingWindow returns a SlidingWindow allowing limit calls per window.
+func NewSlidingWindow(limit int, window time.Duration, opts ...Option) *SlidingWindow {
+	o := buildOptions(opts)
+	return &SlidingWindow{limit: limit, window: window, opts: o, start: o.clock.Now()}
+}
+
+// Allow reports whether a call may proceed now, and counts it if so.
+func (w *SlidingWindow) Allow() bool {
+	now := w.opts.clock.Now()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.window <= 0 {
+		return w.limit > 0
+	}
+
+	// Roll the fixed windows forward
+	if n := now.Sub(w.start) / w.window; n > 0 {
+		if n == 1 {
+			w.prev = w.cur
+		} else {
+			w.prev = 0 // A whole window went by without calls
+		}
+		w.cur = 0
+		w.start = w.start.Add(n * w.window)
+	}
+
+	overlap := 1 - float64(now.Sub(w.start))/float64(w.window)
+	used := float64(w.prev)*overlap + float64(w.cur)
+	if used >= float64(w.limit) {
+		w.opts.observe(false, 0, 0)
+		return false
+	}
+
+	w.cur++
+	w.opts.observe(true, uint(max(float64(w.limit)-used-1, 0)), 0)
+	return true
+}
+
+// SlidingLog allows up to limit calls in any window of the given length,
+// exactly, by remembering when each of the last limit calls was allowed.
+// It costs memory proportional to limit, so prefer SlidingWindow for large
+// limits.
+//
+// It is safe for concurrent use.
+type SlidingLog struct {
+	window time.Duration
+	opts   options
+
+	mu   sync.Mutex
+	log  []time.Time // ring of admission times, oldest at head
+	head int
+	n    int // entries in use
+}
+
+// NewSlidingLog returns a SlidingLog allowing limit calls per window.
+func NewSlidingLog(limit int, window time.Duration, opts ...Option) *SlidingLog {
+	return &SlidingLog{window: window, opts: buildOptions(opts), log: make([]time.Time, max(limit, 0))}
+}
+
+// Allow reports whether a call may proceed now, and counts it if so.
+func (l *SlidingLog) Allow() bool {
+	now := l.opts.clock.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.log) == 0 {
+		l.opts.observe(false, 0, 0)
+		return false
+	}
+
+	if l.n < len(l.log) {
+		l.log[(l.head+l.n)%len(l.log)] = now
+		l.n++
+		l.opts.observe(true, uint(len(l.log)-l.n), 0)
+		return true
+	}
+
+	// Full: the oldest admission must have left the window
+	if now.Sub(l.log[l.head]) < l.window {
+		l.opts.observe(false, 0, 0)
+		return false
+	}
+
+	l.log[l.head] = now
+	l.head = (l.head + 1) % len(l.log)
+	l.opts.observe(true, 0, 0)
+	return true
+}
@@ -5,7 +5,10 @@
 // support context.Context. It isolates faults and avoids blocking on slow operations.
 package timeout
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // SlowFunction defines a function that may run for an unbounded duration.
 type SlowFunction func(string) (string, error)
@@ -76,3 +79,85 @@ func TimeoutWithPrecheck(fn SlowFunction) WithContext {
 		}
 	}
 }
+
+// TimeoutWithGrace wraps a function that can be made to cooperate with
+// cancellation. It runs fn with a context derived from the caller's, so when
+// the caller's context is done fn sees its context done too. It then waits up
+// to grace for fn to clean up and return before abandoning it.
+//
+// Either way the caller gets ctx.Err() once its context is done; grace only
+// bounds how long the caller waits for fn to wind down, which lets legacy
+// code release locks or flush state it was partway through.
+func TimeoutWithGrace(fn WithContext, grace time.Duration) WithContext {
+	return func(ctx context.Context, arg string) (string, error) {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+
+		cctx, cancel := context.WithCancel(ctx)
+
+		ch := make(chan struct {
+			result string
+			err    error
+		}, 1)
+
+		go func() {
+			defer cancel()
+
+			res, err := fn(cctx, arg)
+			ch <- struct {
+				result string
+				err    error
+			}{res, err}
+		}()
+
+		select {
+		case res := <-ch:
+			return res.result, res.err
+		case <-ctx.Done():
+			// fn has been signalled through cctx; give it a moment to stop.
+		}
+
+		t := time.NewTimer(grace)
+		defer t.Stop()
+
+		select {
+		case <-ch:
+			// fn cleaned up in time.
+		case <-t.C:
+			// fn is still running; abandon it in the background.
+		}
+
+		return "", ctx.Err()
+	}
+}
+
+// Run calls fn with ctx and returns its result, or ctx.Err() as soon as ctx
+// is done, even if fn ignores ctx and keeps running in the background. It is
+// the generic form of Timeout for functions that already take a context.
+func Run[T any](ctx context.Context, fn func(context.Context) (T, error)) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+
+	ch := make(chan struct {
+		result T
+		err    error
+	}, 1)
+
+	go func() {
+		res, err := fn(ctx)
+		ch <- struct {
+			result T
+			err    error
+		}{res, err}
+	}()
+
+	select {
+	case res := <-ch:
+		return res.result, res.err
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}